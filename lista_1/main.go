@@ -1,83 +1,47 @@
 package main
 
 import (
-	"math/rand"
+	"context"
+	"flag"
+	"fmt"
+	"lista_1/sim"
 	"os"
 	"strconv"
-	"sync"
-	"sync/atomic"
-	"time"
 )
 
-const (
-	spawnExplorerTick = 50 * time.Millisecond
-	moveExplorerTick  = 50 * time.Millisecond
-	spawnExplorerRate = 0.01
-	moveExplorerRate  = 0.10
-	logBuffer         = 100
-	runTime           = 10 * time.Second
-	cameraTick        = 300 * time.Millisecond
-	cameraBuffer      = 100
-)
-
-func runner(v Vertex, explorerCount *atomic.Uint64, quit *atomic.Bool, maxExplorers int, logChanel chan<- LogPayload) {
-	logger := v.CreateLogger(logChanel)
-
-	for !quit.Load() {
-		spawnTimer := time.NewTimer(spawnExplorerTick)
-		exploreTimer := time.NewTimer(moveExplorerTick)
-
-		if v.explorer == nil {
-			// we don't currently have an explorer, so we can either spawn one or accept one from a neighbor
-			select {
-			case e := <-v.self:
-				v.explorer = e
-				logger.LogExplorerReceived(v.explorer.id)
-			case <-spawnTimer.C:
-				if rand.Float64() < spawnExplorerRate && explorerCount.Load() < uint64(maxExplorers-1) {
-					id := explorerCount.Add(1)
-					v.explorer = &Explorer{id: int(id)}
-					logger.LogExplorerSpawned(v.explorer.id)
-				}
-			}
-		} else {
-			// we have an explorer, so we can try to move it to a neighbor
-			<-exploreTimer.C
-
-			if rand.Float64() < moveExplorerRate {
-				// try to move the explorer to a neighbor
-				select {
-				case v.north <- v.explorer:
-					logger.LogExplorerSend(v.explorer.id, North)
-					v.explorer = nil
-				case v.south <- v.explorer:
-					logger.LogExplorerSend(v.explorer.id, South)
-					v.explorer = nil
-				case v.east <- v.explorer:
-					logger.LogExplorerSend(v.explorer.id, East)
-					v.explorer = nil
-				case v.west <- v.explorer:
-					logger.LogExplorerSend(v.explorer.id, West)
-					v.explorer = nil
-				default:
-					// no neighbor is available, so we just keep the explorer
-				}
-			}
-		}
-
-		spawnTimer.Stop()
-		exploreTimer.Stop()
-	}
-}
-
 func main() {
-	explorerCount := atomic.Uint64{}
-	quit := atomic.Bool{}
+	capacity := flag.Int("capacity", 1, "how many explorers fit on one vertex")
+	capacityMap := flag.String("capacity-map", "", "json file with a list of {x, y, capacity} overriding the capacity of single vertices")
+	drift := flag.String("drift", "", "make every edge along this direction one way: N, S, E or W")
+	edgeMap := flag.String("edges", "", "json file with a list of {x, y, to} one way edges, to is N, S, E or W")
+	open := flag.Bool("open", false, "open boundaries: explorers leave through the edges of the lattice and new ones arrive there")
+	arrivals := flag.Float64("arrival-rate", sim.DefaultArrivalRate, "chance per tick that an explorer arrives at an edge vertex of an open lattice")
+	exits := flag.Float64("exit-rate", sim.DefaultExitRate, "chance that an explorer moving from an edge vertex of an open lattice leaves it")
+	populationMode := flag.String("population", "saturate", "how many explorers live on the lattice: saturate, fixed, density or schedule")
+	populationCount := flag.Int("population-count", 10, "number of explorers of a fixed population")
+	density := flag.Float64("density", 0.3, "explorers per vertex the density population keeps")
+	schedulePath := flag.String("schedule", "", "json file with a list of {at, density} steps for the schedule population, at is a duration like 2s")
+	trajectoriesPath := flag.String("trajectories", "", "write the path of every explorer to PREFIX.csv and PREFIX.json and the mean squared displacement to PREFIX-msd.csv")
+	trail := flag.Int("trail", 0, "draw the last moves of every explorer in its own fading colour, 0 turns trails off")
+	backend := flag.String("backend", "channels", "how explorers move: vertex routines passing them over channels (channels), over a grid of atomic cells (shared) or a routine per explorer taking passive vertices (travellers)")
+	benchFor := flag.Duration("bench", 0, "compare the backends for this long on a fixed population of -population-count explorers instead of running the simulation")
+	recordPath := flag.String("record", "", "record the camera to an asciinema cast file")
+	logSinks := flag.String("log", "log.txt", "comma separated places to write the log to: file paths, stdout, stderr or none")
+	logMaxSize := flag.Int64("log-max-size", 0, "rotate a log file when it grows over this many bytes, 0 never rotates")
+	logKeep := flag.Int("log-keep", 3, "how many rotated log files are kept")
+	logEvents := flag.String("log-events", "all", "comma separated event types written to the log, for example explorerSend")
+	logBufferSize := flag.Int("log-buffer", sim.DefaultLogBuffer, "how many events wait for the logger before the overflow policy kicks in")
+	logOverflow := flag.String("log-overflow", "block", "what a full log buffer does: block the simulation, drop-oldest or drop-newest events")
+	flag.Parse()
+
+	logConfig, err := sim.ParseLogConfig(*logSinks, *logEvents, *logMaxSize, *logKeep, *logBufferSize, *logOverflow)
+	if err != nil {
+		panic(err)
+	}
 
 	n := 10
 	m := 10
-	err := error(nil)
-	args := os.Args[1:]
+	args := flag.Args()
 	if len(args) == 1 {
 		n, err = strconv.Atoi(args[0])
 		if err != nil {
@@ -97,43 +61,60 @@ func main() {
 		panic("Too many arguments")
 	}
 
-	maxExplorers := n * m
-
-	vertices := CreateLattice(n, m)
-	logChannel := make(chan LogPayload, logBuffer)
-	loggerDone := make(chan bool)
-	cameraChanel := make(chan CameraMessage, cameraBuffer)
-	cameraDone := make(chan bool)
+	if n < 1 || m < 1 {
+		panic("Lattice dimensions must be positive")
+	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(n * m)
+	capacities, err := sim.ReadCapacityMap(*capacityMap)
+	if err != nil {
+		panic(err)
+	}
+	oneWay, err := sim.ReadEdgeMap(*edgeMap)
+	if err != nil {
+		panic(err)
+	}
+	opts := sim.Options{
+		N:                n,
+		M:                m,
+		Capacity:         *capacity,
+		CapacityMap:      capacities,
+		Drift:            *drift,
+		OneWay:           oneWay,
+		Open:             *open,
+		ArrivalRate:      *arrivals,
+		ExitRate:         *exits,
+		Population:       *populationMode,
+		PopulationCount:  *populationCount,
+		Density:          *density,
+		SchedulePath:     *schedulePath,
+		Backend:          *backend,
+		Log:              logConfig,
+		Trail:            *trail,
+		TrajectoriesPath: *trajectoriesPath,
+	}
 
-	for i := 0; i < n; i++ {
-		for j := 0; j < m; j++ {
-			go func(v Vertex) {
-				runner(v, &explorerCount, &quit, maxExplorers, logChannel)
-				wg.Done()
-			}(vertices[i][j])
+	if *benchFor > 0 {
+		err := sim.Benchmark(opts, *benchFor, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: benchmark failed:", err)
+			os.Exit(1)
 		}
+		return
 	}
+	output, closeOutput, err := sim.OpenOutput(*recordPath, n, m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: could not open the recording:", err)
+		os.Exit(1)
+	}
+	defer closeOutput()
+	opts.Output = output
 
-	go func() {
-		loggerRun(logChannel, cameraChanel)
-		loggerDone <- true
-	}()
-
-	go func() {
-		camera := NewCamera(cameraChanel, n, m)
-		camera.Start()
-		cameraDone <- true
-	}()
-
-	time.Sleep(runTime)
-	quit.Store(true)
-	wg.Wait()
-
-	close(logChannel)
-
-	<-loggerDone
-	<-cameraDone
+	simulation, err := sim.New(opts)
+	if err != nil {
+		panic(err)
+	}
+	err = simulation.Run(context.Background())
+	if err != nil {
+		panic(err)
+	}
 }
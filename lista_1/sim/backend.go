@@ -0,0 +1,140 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runParams are what the routines of the vertices share, whatever the backend
+type runParams struct {
+	population *Population
+	quit       *atomic.Bool
+	// boundary is nil on a closed lattice
+	boundary *OpenBoundary
+	logQueue *LogQueue
+	// a vertex looks at spawning and moving explorers once per tick
+	spawnTick time.Duration
+	moveTick  time.Duration
+}
+
+var backends = map[string]func(vertices [][]Vertex, params *runParams, wg *sync.WaitGroup){
+	"channels":   startChannels,
+	"shared":     startShared,
+	"travellers": startTravellers,
+}
+
+// startBackend starts the routines of all vertices, wg is done when they stopped
+func startBackend(backend string, vertices [][]Vertex, params *runParams, wg *sync.WaitGroup) error {
+	start, ok := backends[backend]
+	if !ok {
+		return fmt.Errorf("unknown backend %q, use channels, shared or travellers", backend)
+	}
+	start(vertices, params, wg)
+	return nil
+}
+
+// startChannels runs every vertex in its own routine, explorers go from one
+// vertex to the next over the unbuffered channels of the lattice
+func startChannels(vertices [][]Vertex, params *runParams, wg *sync.WaitGroup) {
+	for y := range vertices {
+		for x := range vertices[y] {
+			wg.Add(1)
+			go func(v Vertex) {
+				runner(v, params)
+				wg.Done()
+			}(vertices[y][x])
+		}
+	}
+}
+
+// runner runs one vertex of the channel backend
+func runner(v Vertex, params *runParams) {
+	logger := v.CreateLogger(params.logQueue)
+	sides := v.openSides()
+	population := params.population
+	boundary := params.boundary
+
+	for !params.quit.Load() {
+		spawnTimer := time.NewTimer(params.spawnTick)
+		exploreTimer := time.NewTimer(params.moveTick)
+
+		// a nil channel is never ready, so a full vertex neither accepts nor
+		// spawns explorers and an empty one has nothing to move
+		var incoming <-chan *Explorer
+		var spawn <-chan time.Time
+		if len(v.explorers) < v.capacity {
+			incoming = v.self
+			spawn = spawnTimer.C
+		}
+		var explore <-chan time.Time
+		if len(v.explorers) > 0 {
+			explore = exploreTimer.C
+		}
+
+		select {
+		case e := <-incoming:
+			v.explorers = append(v.explorers, e)
+			logger.LogExplorerReceived(e.id)
+		case <-spawn:
+			if boundary == nil {
+				if rand.Float64() < population.spawnChance() {
+					id, ok := population.admit()
+					if ok {
+						v.explorers = append(v.explorers, &Explorer{id: id})
+						logger.LogExplorerSpawned(id)
+					}
+				}
+			} else if len(sides) > 0 && rand.Float64() < boundary.arrivalRate {
+				// explorers only come in from outside through the edge vertices
+				id, ok := population.admit()
+				if ok {
+					v.explorers = append(v.explorers, &Explorer{id: id})
+					logger.LogExplorerEntered(id, sides[rand.Intn(len(sides))])
+				}
+			}
+		case <-explore:
+			if rand.Float64() < population.despawnChance() {
+				// there are more explorers than the population wants
+				i := rand.Intn(len(v.explorers))
+				id := v.explorers[i].id
+				v.explorers = append(v.explorers[:i], v.explorers[i+1:]...)
+				population.leave()
+				logger.LogExplorerDespawned(id)
+			} else if rand.Float64() < moveExplorerRate {
+				i := rand.Intn(len(v.explorers))
+				e := v.explorers[i]
+				if boundary != nil && len(sides) > 0 && rand.Float64() < boundary.exitRate {
+					v.explorers = append(v.explorers[:i], v.explorers[i+1:]...)
+					population.leave()
+					logger.LogExplorerExited(e.id, sides[rand.Intn(len(sides))])
+					break
+				}
+
+				// try to move one of the explorers to a neighbor
+				direction := None
+				select {
+				case v.exit(North) <- e:
+					direction = North
+				case v.exit(South) <- e:
+					direction = South
+				case v.exit(East) <- e:
+					direction = East
+				case v.exit(West) <- e:
+					direction = West
+				default:
+					// no neighbor is available, so we just keep the explorer
+				}
+				if direction != None {
+					v.explorers = append(v.explorers[:i], v.explorers[i+1:]...)
+					logger.LogExplorerSend(e.id, direction)
+				}
+			}
+		}
+
+		spawnTimer.Stop()
+		exploreTimer.Stop()
+	}
+}
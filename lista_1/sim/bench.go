@@ -0,0 +1,180 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// benchResult is what one backend did in a benchmark run
+type benchResult struct {
+	backend  string
+	moves    int
+	duration time.Duration
+	// fairness is Jain's index of the moves per explorer, 1 when every
+	// explorer moved as often as the others and 1/n when one did all moves
+	fairness float64
+	min      int
+	max      int
+	// goroutines is the most routines the process had during the run
+	goroutines int
+	// latency is the time between two moves of an explorer, on average and
+	// for the slowest percent of the moves
+	latency   time.Duration
+	latency99 time.Duration
+}
+
+// benchSetup builds a fresh lattice with its explorers for one benchmark run
+type benchSetup func(logQueue *LogQueue) ([][]Vertex, *Population, error)
+
+// Benchmark compares the backends on a fixed population of
+// opts.PopulationCount explorers on the lattice of opts, it is the command line
+// face of BenchmarkBackend
+func Benchmark(opts Options, duration time.Duration, out io.Writer) error {
+	opts = opts.withDefaults()
+	if opts.N < 1 || opts.M < 1 {
+		return fmt.Errorf("lattice dimensions must be positive")
+	}
+	fmt.Fprintf(out, "INFO: benchmark of a %dx%d lattice with %d explorers, %s per backend\n", opts.N, opts.M, opts.PopulationCount, duration)
+	return benchmark(benchLattice(opts), duration, out)
+}
+
+// benchLattice builds the lattice of opts with its fixed population
+func benchLattice(opts Options) benchSetup {
+	return func(logQueue *LogQueue) ([][]Vertex, *Population, error) {
+		vertices := CreateLattice(opts.N, opts.M)
+		maxExplorers, err := SetCapacities(vertices, opts.Capacity, opts.CapacityMap)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = SetEdges(vertices, opts.Drift, opts.OneWay)
+		if err != nil {
+			return nil, nil, err
+		}
+		population, err := parsePopulation("fixed", opts.PopulationCount, 0, "", int64(maxExplorers-1), opts.N*opts.M)
+		if err != nil {
+			return nil, nil, err
+		}
+		population.placeFixed(vertices, logQueue)
+		return vertices, population, nil
+	}
+}
+
+// benchmark runs every backend on the same setup for the duration, with a
+// much shorter tick than the simulation, and prints how many moves they made
+// and how evenly the moves were spread over the explorers
+func benchmark(setup benchSetup, duration time.Duration, out io.Writer) error {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "%-10s %10s %12s %9s %6s %6s %10s %12s %12s\n", "backend", "moves", "moves/s", "fairness", "min", "max", "goroutines", "latency", "latency p99")
+	for _, name := range names {
+		result, err := benchBackend(name, setup, duration, 0)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%-10s %10d %12.0f %9.3f %6d %6d %10d %12s %12s\n", result.backend, result.moves,
+			float64(result.moves)/result.duration.Seconds(), result.fairness, result.min, result.max,
+			result.goroutines, result.latency.Round(time.Microsecond), result.latency99.Round(time.Microsecond))
+	}
+	return nil
+}
+
+// benchBackend runs one backend for the duration or until the explorers made
+// the given number of moves, 0 moves runs for the whole duration
+func benchBackend(backend string, setup benchSetup, duration time.Duration, stopAfter int) (benchResult, error) {
+	result := benchResult{backend: backend}
+	logQueue := NewLogQueue(DefaultLogBuffer, OverflowBlock)
+
+	// the moves are counted from the log, so both backends pay for logging
+	moves := make(map[int]int)
+	lastMove := make(map[int]time.Time)
+	var intervals []time.Duration
+	// the run looks at the total while the counter still holds the map
+	total := atomic.Int64{}
+	countDone := make(chan bool)
+	go func() {
+		for log := range logQueue.Messages() {
+			switch log.logType {
+			case ExplorerSpawned:
+				// an explorer that never moves counts too
+				moves[log.expId] += 0
+				lastMove[log.expId] = log.timestamp
+			case ExplorerSend:
+				moves[log.expId] += 1
+				total.Add(1)
+				if last, ok := lastMove[log.expId]; ok {
+					intervals = append(intervals, log.timestamp.Sub(last))
+				}
+				lastMove[log.expId] = log.timestamp
+			}
+		}
+		countDone <- true
+	}()
+
+	vertices, population, err := setup(logQueue)
+	if err != nil {
+		logQueue.Close()
+		<-countDone
+		return result, err
+	}
+
+	quit := atomic.Bool{}
+	params := &runParams{population: population, quit: &quit, logQueue: logQueue, spawnTick: benchTick, moveTick: benchTick}
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	err = startBackend(backend, vertices, params, &wg)
+	if err != nil {
+		return result, err
+	}
+	for time.Since(start) < duration && (stopAfter == 0 || total.Load() < int64(stopAfter)) {
+		time.Sleep(benchSample)
+		if goroutines := runtime.NumGoroutine(); goroutines > result.goroutines {
+			result.goroutines = goroutines
+		}
+	}
+	quit.Store(true)
+	wg.Wait()
+	result.duration = time.Since(start)
+	logQueue.Close()
+	<-countDone
+
+	sum, squares := 0.0, 0.0
+	result.min = -1
+	for _, count := range moves {
+		result.moves += count
+		sum += float64(count)
+		squares += float64(count) * float64(count)
+		if result.min < 0 || count < result.min {
+			result.min = count
+		}
+		if count > result.max {
+			result.max = count
+		}
+	}
+	if squares > 0 {
+		result.fairness = sum * sum / (float64(len(moves)) * squares)
+	}
+	if result.min < 0 {
+		result.min = 0
+	}
+	if len(intervals) > 0 {
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i] < intervals[j]
+		})
+		var total time.Duration
+		for _, interval := range intervals {
+			total += interval
+		}
+		result.latency = total / time.Duration(len(intervals))
+		result.latency99 = intervals[len(intervals)*99/100]
+	}
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package sim
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// BenchmarkBackend runs every backend on the same lattice until the explorers
+// made b.N moves, so ns/op is the time of one move
+func BenchmarkBackend(b *testing.B) {
+	setup := benchLattice(Options{N: 8, M: 8, Capacity: 1, PopulationCount: 16})
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		b.Run(name, func(b *testing.B) {
+			// a backend that stops moving ends the run instead of hanging it
+			result, err := benchBackend(name, setup, 10*time.Second, b.N)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if result.moves == 0 {
+				b.Fatal("no explorer moved")
+			}
+			b.ReportMetric(float64(result.duration.Nanoseconds())/float64(result.moves), "ns/op")
+			b.ReportMetric(result.fairness, "fairness")
+			b.ReportMetric(float64(result.goroutines), "goroutines")
+			b.ReportMetric(float64(result.latency99.Microseconds()), "p99-µs")
+		})
+	}
+}
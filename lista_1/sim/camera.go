@@ -0,0 +1,445 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TERM_RESET = "\033[0m"
+	TERM_RED   = "\033[31m"
+)
+
+type Camera struct {
+	cameraChanel <-chan CameraMessage
+	board        [][][]int
+	n            int
+	m            int
+	crossedEdges *crossedEdges
+	oneWay       *oneWayEdges
+	out          io.Writer
+	// trail is how many of the last moves of every explorer are drawn, the
+	// moves are in trails by explorer id
+	trail  int
+	trails map[int][]trailMove
+}
+
+type trailMove struct {
+	fromX int
+	fromY int
+	toX   int
+	toY   int
+}
+
+type CameraMessage struct {
+	messageType CameraMessageType
+	x           int
+	y           int
+	expId       int
+	xHelper     int
+	yHelper     int
+}
+
+type CameraMessageType int
+
+const (
+	CamExplorerSpawned CameraMessageType = iota
+	CamExplorerMoved
+	CamExplorerEntered
+	CamExplorerExited
+	CamExplorerRemoved
+)
+
+func RecordSpawnExplorer(expId, x, y int) CameraMessage {
+	return CameraMessage{expId: expId, x: x, y: y, messageType: CamExplorerSpawned}
+}
+
+func RecordMoveExplorer(expId, fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{expId: expId, x: fromX, y: fromY, xHelper: toX, yHelper: toY, messageType: CamExplorerMoved}
+}
+
+// RecordEnterExplorer records an explorer coming from outside of the lattice
+// at (fromX, fromY) to its edge vertex at (toX, toY)
+func RecordEnterExplorer(expId, fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{expId: expId, x: fromX, y: fromY, xHelper: toX, yHelper: toY, messageType: CamExplorerEntered}
+}
+
+// RecordExitExplorer records an explorer leaving the lattice from its edge
+// vertex at (fromX, fromY) to the outside at (toX, toY)
+func RecordExitExplorer(expId, fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{expId: expId, x: fromX, y: fromY, xHelper: toX, yHelper: toY, messageType: CamExplorerExited}
+}
+
+func RecordRemoveExplorer(expId, x, y int) CameraMessage {
+	return CameraMessage{expId: expId, x: x, y: y, messageType: CamExplorerRemoved}
+}
+
+// frameSize is the width and height of the frames PrintBoard draws: 3
+// characters per vertex wide, 2 lines per vertex tall and one more for each
+// border, with the explorer count under it
+func frameSize(n, m int) (int, int) {
+	return 3*n + 1, 2*m + 2
+}
+
+func (c Camera) PrintBoard() {
+	// the frame is written at once, so a recording gets it as one event
+	frame := &strings.Builder{}
+	c.PrintBoardSeparator(frame, c.crossedEdges.north)
+	trailEast, trailSouth := c.trailEdges()
+	bottomRow := "+"
+	for y := 0; y < c.m; y++ {
+		if c.crossedEdges.west[y] {
+			fmt.Fprintf(frame, "%s|%s", TERM_RED, TERM_RESET)
+		} else {
+			fmt.Fprint(frame, "|")
+		}
+		for x := 0; x < c.n; x++ {
+			vertId := y*c.n + x
+
+			fmt.Fprint(frame, c.cell(c.board[y][x]))
+
+			if x < c.n-1 {
+				edge, arrow := "|", arrows[c.oneWay.east[vertId]]
+				if arrow != "" {
+					edge = arrow
+				}
+				if c.crossedEdges.east[vertId] {
+					fmt.Fprintf(frame, "%s%s%s", TERM_RED, edge, TERM_RESET)
+				} else if colour, ok := trailEast[vertId]; ok {
+					fmt.Fprintf(frame, "%s%s%s", colour, edge, TERM_RESET)
+				} else if arrow != "" {
+					fmt.Fprint(frame, arrow)
+				} else {
+					fmt.Fprintf(frame, " ")
+				}
+			} else if c.crossedEdges.east[vertId] {
+				fmt.Fprintf(frame, "%s|%s\n", TERM_RED, TERM_RESET)
+			} else {
+				fmt.Fprintln(frame, "|")
+			}
+
+			if y < c.m-1 {
+				edge, arrow := "--", arrows[c.oneWay.south[vertId]]
+				if arrow != "" {
+					edge = arrow
+				}
+				if c.crossedEdges.south[vertId] {
+					bottomRow += fmt.Sprintf("%s%s%s+", TERM_RED, edge, TERM_RESET)
+				} else if colour, ok := trailSouth[vertId]; ok {
+					bottomRow += fmt.Sprintf("%s%s%s+", colour, edge, TERM_RESET)
+				} else if arrow != "" {
+					bottomRow += arrow + "+"
+				} else {
+					bottomRow += "  +"
+				}
+			}
+		}
+		if y < c.m-1 {
+			fmt.Fprintln(frame, bottomRow)
+			bottomRow = "+"
+		}
+	}
+	c.PrintBoardSeparator(frame, c.crossedEdges.south[(c.m-1)*c.n:])
+	fmt.Fprintf(frame, "explorers: %d\n", c.population())
+	c.ClearEdges()
+	io.WriteString(c.out, frame.String())
+}
+
+func (c Camera) Start() {
+	ticker := time.NewTicker(cameraTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.PrintBoard()
+		case msg, ok := <-c.cameraChanel:
+			switch msg.messageType {
+			case CamExplorerSpawned:
+				c.board[msg.y][msg.x] = append(c.board[msg.y][msg.x], msg.expId)
+			case CamExplorerMoved:
+				c.board[msg.y][msg.x] = removeId(c.board[msg.y][msg.x], msg.expId)
+				c.board[msg.yHelper][msg.xHelper] = append(c.board[msg.yHelper][msg.xHelper], msg.expId)
+
+				c.crossedEdges.Mark(msg.x, msg.y, msg.xHelper, msg.yHelper)
+				c.addTrail(msg.expId, trailMove{msg.x, msg.y, msg.xHelper, msg.yHelper})
+			case CamExplorerEntered:
+				c.board[msg.yHelper][msg.xHelper] = append(c.board[msg.yHelper][msg.xHelper], msg.expId)
+				c.crossedEdges.Mark(msg.x, msg.y, msg.xHelper, msg.yHelper)
+			case CamExplorerExited:
+				c.board[msg.y][msg.x] = removeId(c.board[msg.y][msg.x], msg.expId)
+				c.crossedEdges.Mark(msg.x, msg.y, msg.xHelper, msg.yHelper)
+				delete(c.trails, msg.expId)
+			case CamExplorerRemoved:
+				c.board[msg.y][msg.x] = removeId(c.board[msg.y][msg.x], msg.expId)
+				delete(c.trails, msg.expId)
+			}
+
+			if !ok {
+				return
+			}
+		}
+	}
+
+}
+
+// addTrail remembers the move of the explorer, only the last trail moves are kept
+func (c Camera) addTrail(expId int, move trailMove) {
+	if c.trail == 0 {
+		return
+	}
+	moves := append(c.trails[expId], move)
+	if len(moves) > c.trail {
+		moves = moves[len(moves)-c.trail:]
+	}
+	c.trails[expId] = moves
+}
+
+// trailEdges returns the colour of every edge on a trail by the vertex the
+// edge is stored at, like in crossedEdges. Newer moves are drawn over older ones.
+func (c Camera) trailEdges() (east, south map[int]string) {
+	east = make(map[int]string)
+	south = make(map[int]string)
+	ids := make([]int, 0, len(c.trails))
+	for expId := range c.trails {
+		ids = append(ids, expId)
+	}
+	// the same order every frame, so crossing trails don't flicker
+	sort.Ints(ids)
+	for age := c.trail - 1; age >= 0; age-- {
+		for _, expId := range ids {
+			moves := c.trails[expId]
+			i := len(moves) - 1 - age
+			if i < 0 {
+				continue
+			}
+			move := moves[i]
+			id, isEast, ok := edgeOf(c.n, move.fromX, move.fromY, move.toX, move.toY)
+			if !ok {
+				continue
+			}
+			if isEast {
+				east[id] = trailColour(expId, age, c.trail)
+			} else {
+				south[id] = trailColour(expId, age, c.trail)
+			}
+		}
+	}
+	return east, south
+}
+
+// trailBases are the colours of the explorers as red, green and blue levels
+// of the 6x6x6 cube of the 256 colour terminal palette
+var trailBases = [][3]int{
+	{5, 1, 1}, {1, 5, 1}, {1, 2, 5}, {5, 5, 1}, {5, 1, 5}, {1, 5, 5},
+	{5, 3, 0}, {3, 5, 0}, {0, 3, 5}, {5, 0, 3}, {3, 0, 5}, {0, 5, 3},
+}
+
+// trailColour is the colour of the explorer, dimmer for older moves
+func trailColour(expId, age, trail int) string {
+	base := trailBases[expId%len(trailBases)]
+	level := func(c int) int {
+		return (c*(trail-age) + trail/2) / trail
+	}
+	index := 16 + 36*level(base[0]) + 6*level(base[1]) + level(base[2])
+	return fmt.Sprintf("\033[38;5;%dm", index)
+}
+
+// cell shows the explorers of a vertex, in trail mode a lone explorer has the
+// colour of its trail
+func (c Camera) cell(ids []int) string {
+	if c.trail > 0 && len(ids) == 1 {
+		return trailColour(ids[0], 0, c.trail) + occupancy(ids) + TERM_RESET
+	}
+	return occupancy(ids)
+}
+
+// population counts the explorers on the board
+func (c Camera) population() int {
+	count := 0
+	for y := range c.board {
+		for x := range c.board[y] {
+			count += len(c.board[y][x])
+		}
+	}
+	return count
+}
+
+// occupancy shows the id of a lone explorer and the number of explorers when
+// there are more of them, a cell is only two characters wide
+func occupancy(ids []int) string {
+	switch {
+	case len(ids) == 0:
+		return "  "
+	case len(ids) == 1:
+		return fmt.Sprintf("%02d", ids[0]%100)
+	case len(ids) < 10:
+		return fmt.Sprintf("x%d", len(ids))
+	default:
+		return "++"
+	}
+}
+
+func removeId(ids []int, id int) []int {
+	for i := range ids {
+		if ids[i] == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+func (c Camera) ClearEdges() {
+	c.crossedEdges.Clear()
+}
+
+// PrintBoardSeparator prints the top or the bottom border, crossed says which
+// parts of it explorers went through since the last frame
+func (c Camera) PrintBoardSeparator(w io.Writer, crossed []bool) {
+	fmt.Fprint(w, "+")
+	for i := 0; i < c.n; i++ {
+		if crossed[i] {
+			fmt.Fprintf(w, "%s--%s+", TERM_RED, TERM_RESET)
+		} else {
+			fmt.Fprint(w, "--+")
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// NewCamera draws the lattice n wide and m tall, oneWay are the arrows of its
+// one way edges
+func NewCamera(cameraChanel <-chan CameraMessage, n, m int, out io.Writer, trail int, oneWay *oneWayEdges) Camera {
+	board := make([][][]int, m)
+	for y := 0; y < m; y++ {
+		board[y] = make([][]int, n)
+	}
+
+	crossedEdges := newCrossedEdges(n, m)
+
+	return Camera{cameraChanel: cameraChanel, board: board, n: n, m: m, crossedEdges: crossedEdges, oneWay: oneWay, out: out, trail: trail, trails: make(map[int][]trailMove)}
+}
+
+// crossedEdges stores one flag per real lattice edge instead of a full
+// adjacency matrix: east[id] is the edge between vertex id and its east
+// neighbour and south[id] the edge between vertex id and its south neighbour.
+// The vertices crossed since the last Clear are remembered, so clearing costs
+// only as much as the number of edges that were actually crossed.
+// On an open lattice the east and south sides of the last column and row lead
+// outside, the north and west sides of the first ones are in north[x] and west[y].
+type crossedEdges struct {
+	n       int
+	east    []bool
+	south   []bool
+	north   []bool
+	west    []bool
+	touched []int
+}
+
+func newCrossedEdges(n, m int) *crossedEdges {
+	return &crossedEdges{n: n, east: make([]bool, n*m), south: make([]bool, n*m), north: make([]bool, n), west: make([]bool, m)}
+}
+
+func (e *crossedEdges) Mark(fromX, fromY, toX, toY int) {
+	switch {
+	case fromY < 0 || toY < 0:
+		e.north[fromX] = true
+		return
+	case fromX < 0 || toX < 0:
+		e.west[fromY] = true
+		return
+	}
+
+	id, east, ok := edgeOf(e.n, fromX, fromY, toX, toY)
+	if !ok {
+		return
+	}
+	if east {
+		e.east[id] = true
+	} else {
+		e.south[id] = true
+	}
+	e.touched = append(e.touched, id)
+}
+
+// edgeOf returns the vertex the edge between two neighbours is stored at and
+// whether it is the east or the south edge of that vertex
+func edgeOf(n, fromX, fromY, toX, toY int) (id int, east bool, ok bool) {
+	// an edge is always stored at its north-west end
+	x, y := fromX, fromY
+	if toX < x || toY < y {
+		x, y = toX, toY
+	}
+	id = y*n + x
+
+	switch {
+	case fromY == toY && (fromX-toX == 1 || toX-fromX == 1):
+		return id, true, true
+	case fromX == toX && (fromY-toY == 1 || toY-fromY == 1):
+		return id, false, true
+	default:
+		return 0, false, false
+	}
+}
+
+func (e *crossedEdges) Clear() {
+	for _, id := range e.touched {
+		e.east[id] = false
+		e.south[id] = false
+	}
+	e.touched = e.touched[:0]
+	for i := range e.north {
+		e.north[i] = false
+	}
+	for i := range e.west {
+		e.west[i] = false
+	}
+}
+
+// arrows are drawn on the one way edges, the east edges are one character
+// wide and the south edges two
+var arrows = map[LogDirection]string{
+	East:  ">",
+	West:  "<",
+	South: "vv",
+	North: "^^",
+}
+
+// oneWayEdges stores the direction of every one way edge the same way
+// crossedEdges stores the crossed ones, None is an edge that goes both ways
+type oneWayEdges struct {
+	east  []LogDirection
+	south []LogDirection
+}
+
+// oneWayEdgesOf finds the one way edges of the lattice
+func oneWayEdgesOf(vertices [][]Vertex) *oneWayEdges {
+	m := len(vertices)
+	n := len(vertices[0])
+	e := &oneWayEdges{east: make([]LogDirection, n*m), south: make([]LogDirection, n*m)}
+	for y := 0; y < m; y++ {
+		for x := 0; x < n; x++ {
+			v := &vertices[y][x]
+			if x < n-1 {
+				switch east := &vertices[y][x+1]; {
+				case !v.blocked[East] && east.blocked[West]:
+					e.east[v.id] = East
+				case v.blocked[East] && !east.blocked[West]:
+					e.east[v.id] = West
+				}
+			}
+			if y < m-1 {
+				switch south := &vertices[y+1][x]; {
+				case !v.blocked[South] && south.blocked[North]:
+					e.south[v.id] = South
+				case v.blocked[South] && !south.blocked[North]:
+					e.south[v.id] = North
+				}
+			}
+		}
+	}
+	return e
+}
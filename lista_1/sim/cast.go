@@ -0,0 +1,112 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CastRecorder passes everything written to it on to out and records it in
+// an asciinema v2 cast file, together with the time it was written at
+type CastRecorder struct {
+	mu    sync.Mutex
+	out   io.Writer
+	file  *os.File
+	w     *bufio.Writer
+	start time.Time
+	err   error
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+func NewCastRecorder(path string, out io.Writer, width, height int) (*CastRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &CastRecorder{out: out, file: file, w: bufio.NewWriter(file), start: time.Now()}
+	header := castHeader{Version: 2, Width: width, Height: height, Timestamp: r.start.Unix()}
+	if term := os.Getenv("TERM"); term != "" {
+		header.Env = map[string]string{"TERM": term}
+	}
+	data, err := json.Marshal(header)
+	if err == nil {
+		_, err = r.w.Write(append(data, '\n'))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CastRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.out.Write(p)
+	if r.err == nil {
+		// the player doesn't go back to the start of the line on a new line
+		data := strings.ReplaceAll(string(p), "\n", "\r\n")
+		elapsed := math.Round(time.Since(r.start).Seconds()*1e6) / 1e6
+		var event []byte
+		event, r.err = json.Marshal([]interface{}{elapsed, "o", data})
+		if r.err == nil {
+			_, r.err = r.w.Write(append(event, '\n'))
+		}
+	}
+	return n, err
+}
+
+// Close writes the rest of the recording, it reports the first error the
+// recording ran into
+func (r *CastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.err
+	if flushErr := r.w.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OpenOutput returns where the camera and main print to, it is the terminal
+// and a recording of it when a path is given
+func OpenOutput(recordPath string, n, m int) (io.Writer, func(), error) {
+	if recordPath == "" {
+		return os.Stdout, func() {}, nil
+	}
+
+	width, height := frameSize(n, m)
+	if width < 80 {
+		// room for the INFO lines of main
+		width = 80
+	}
+	recorder, err := NewCastRecorder(recordPath, os.Stdout, width, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recorder, func() {
+		err := recorder.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not write the recording:", err)
+		}
+	}, nil
+}
@@ -0,0 +1,33 @@
+package sim
+
+import "testing"
+
+func TestEventHubDropsSlowSubscriber(t *testing.T) {
+	hub := newEventHub()
+	slow, reader := hub.Subscribe(), hub.Subscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(LogPayload{})
+		<-reader
+	}
+	if hub.dropped != 1 {
+		t.Errorf("%d subscribers dropped, want the slow one", hub.dropped)
+	}
+	buffered := 0
+	for range slow {
+		buffered++
+	}
+	if buffered != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events before its channel closed, want %d", buffered, subscriberBuffer)
+	}
+
+	// the hub goes on with the subscriber that keeps reading
+	hub.Publish(LogPayload{})
+	if _, ok := <-reader; !ok {
+		t.Fatal("reader was dropped")
+	}
+	hub.Close()
+	if _, ok := <-reader; ok {
+		t.Error("reader still open after Close")
+	}
+}
@@ -0,0 +1,289 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Explorer struct {
+	id int
+}
+
+type Vertex struct {
+	id        int
+	x         int
+	y         int
+	explorers []*Explorer
+	capacity  int
+	self      <-chan *Explorer
+	north     chan<- *Explorer
+	south     chan<- *Explorer
+	east      chan<- *Explorer
+	west      chan<- *Explorer
+	// blocked are the directions explorers can't leave the vertex in, the
+	// edges there are one way towards it
+	blocked map[LogDirection]bool
+}
+
+// OpenBoundary lets explorers leave the lattice through the outer sides of
+// the edge vertices and new ones arrive there, instead of being born inside
+type OpenBoundary struct {
+	// arrivalRate is the chance per spawn tick that an explorer enters an edge vertex
+	arrivalRate float64
+	// exitRate is the chance that an explorer moving from an edge vertex leaves
+	exitRate float64
+}
+
+// openSides are the directions in which the vertex has no neighbour
+func (v *Vertex) openSides() []LogDirection {
+	var sides []LogDirection
+	if v.north == nil {
+		sides = append(sides, North)
+	}
+	if v.south == nil {
+		sides = append(sides, South)
+	}
+	if v.east == nil {
+		sides = append(sides, East)
+	}
+	if v.west == nil {
+		sides = append(sides, West)
+	}
+	return sides
+}
+
+// exit is the channel to the neighbour in the direction, nil when there is
+// none or the edge to it is one way towards this vertex
+func (v *Vertex) exit(direction LogDirection) chan<- *Explorer {
+	if v.blocked[direction] {
+		return nil
+	}
+	switch direction {
+	case North:
+		return v.north
+	case South:
+		return v.south
+	case East:
+		return v.east
+	case West:
+		return v.west
+	default:
+		return nil
+	}
+}
+
+// step returns the coordinates one step from (x,y) in the direction
+func step(x, y int, direction LogDirection) (int, int) {
+	switch direction {
+	case North:
+		return x, y - 1
+	case South:
+		return x, y + 1
+	case East:
+		return x + 1, y
+	case West:
+		return x - 1, y
+	default:
+		return x, y
+	}
+}
+
+func opposite(direction LogDirection) LogDirection {
+	switch direction {
+	case North:
+		return South
+	case South:
+		return North
+	case East:
+		return West
+	case West:
+		return East
+	default:
+		return None
+	}
+}
+
+func parseDirection(name string) (LogDirection, error) {
+	switch name {
+	case "N":
+		return North, nil
+	case "S":
+		return South, nil
+	case "E":
+		return East, nil
+	case "W":
+		return West, nil
+	default:
+		return None, fmt.Errorf("unknown direction %q, use N, S, E or W", name)
+	}
+}
+
+// CapacityConfig overrides the capacity of one vertex, the capacity is the
+// number of explorers that can stay on the vertex at the same time
+type CapacityConfig struct {
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	Capacity int `json:"capacity"`
+}
+
+// ReadCapacityMap reads a json list of per vertex capacities
+func ReadCapacityMap(path string) ([]CapacityConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var capacities []CapacityConfig
+	err = json.Unmarshal(data, &capacities)
+	if err != nil {
+		return nil, fmt.Errorf("capacity map %s: %w", path, err)
+	}
+	return capacities, nil
+}
+
+// SetCapacities gives every vertex the default capacity and then applies the
+// per vertex overrides, it returns how many explorers fit on the whole lattice
+func SetCapacities(vertices [][]Vertex, capacity int, overrides []CapacityConfig) (int, error) {
+	if capacity < 1 {
+		return 0, fmt.Errorf("vertex capacity has to be at least 1")
+	}
+	m := len(vertices)
+	n := len(vertices[0])
+	for y := 0; y < m; y++ {
+		for x := 0; x < n; x++ {
+			vertices[y][x].capacity = capacity
+		}
+	}
+	for _, c := range overrides {
+		if c.X < 0 || c.X >= n || c.Y < 0 || c.Y >= m {
+			return 0, fmt.Errorf("capacity of vertex (%d,%d) is given outside of the %dx%d lattice", c.X, c.Y, n, m)
+		}
+		if c.Capacity < 1 {
+			return 0, fmt.Errorf("capacity of vertex (%d,%d) has to be at least 1", c.X, c.Y)
+		}
+		vertices[c.Y][c.X].capacity = c.Capacity
+	}
+
+	total := 0
+	for y := 0; y < m; y++ {
+		for x := 0; x < n; x++ {
+			total += vertices[y][x].capacity
+		}
+	}
+	return total, nil
+}
+
+// EdgeConfig makes the edge from (x,y) to its neighbour in the direction To
+// one way, explorers can't cross it the other way. It overrides the drift.
+type EdgeConfig struct {
+	X  int    `json:"x"`
+	Y  int    `json:"y"`
+	To string `json:"to"`
+}
+
+// ReadEdgeMap reads a json list of one way edges
+func ReadEdgeMap(path string) ([]EdgeConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var edges []EdgeConfig
+	err = json.Unmarshal(data, &edges)
+	if err != nil {
+		return nil, fmt.Errorf("edge map %s: %w", path, err)
+	}
+	return edges, nil
+}
+
+// SetEdges makes every edge along the drift one way, like a conveyor, and then
+// the single edges. An empty drift leaves the other edges going both ways.
+func SetEdges(vertices [][]Vertex, drift string, edges []EdgeConfig) error {
+	m := len(vertices)
+	n := len(vertices[0])
+	if drift != "" {
+		direction, err := parseDirection(drift)
+		if err != nil {
+			return fmt.Errorf("drift: %w", err)
+		}
+		for y := 0; y < m; y++ {
+			for x := 0; x < n; x++ {
+				vertices[y][x].block(opposite(direction))
+			}
+		}
+	}
+
+	given := make(map[[2]int]bool)
+	for _, e := range edges {
+		to, err := parseDirection(e.To)
+		if err != nil {
+			return fmt.Errorf("one way edge from (%d,%d): %w", e.X, e.Y, err)
+		}
+		x, y := step(e.X, e.Y, to)
+		if e.X < 0 || e.X >= n || e.Y < 0 || e.Y >= m || x < 0 || x >= n || y < 0 || y >= m {
+			return fmt.Errorf("one way edge from (%d,%d) to %s is outside of the %dx%d lattice", e.X, e.Y, to, n, m)
+		}
+		from, next := &vertices[e.Y][e.X], &vertices[y][x]
+		key := [2]int{from.id, next.id}
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if given[key] {
+			return fmt.Errorf("the edge between (%d,%d) and (%d,%d) is given twice", e.X, e.Y, x, y)
+		}
+		given[key] = true
+		delete(from.blocked, to)
+		next.block(opposite(to))
+	}
+	return nil
+}
+
+func (v *Vertex) block(direction LogDirection) {
+	if v.blocked == nil {
+		v.blocked = make(map[LogDirection]bool)
+	}
+	v.blocked[direction] = true
+}
+
+// CreateLattice builds a lattice n vertices wide and m vertices tall, indexed as vertices[y][x]
+func CreateLattice(n, m int) [][]Vertex {
+	// create all edges first and then create all vertices
+	edges := make([]chan *Explorer, n*m)
+
+	for i := 0; i < n*m; i++ {
+		edges[i] = make(chan *Explorer)
+	}
+
+	vertices := make([][]Vertex, m)
+	for y := 0; y < m; y++ {
+		vertices[y] = make([]Vertex, n)
+		for x := 0; x < n; x++ {
+			vertices[y][x] = Vertex{id: y*n + x, x: x, y: y, capacity: 1}
+		}
+	}
+
+	for x := 0; x < n; x++ {
+		for y := 0; y < m; y++ {
+			if y > 0 {
+				vertices[y][x].north = edges[(y-1)*n+x]
+			}
+			if y < m-1 {
+				vertices[y][x].south = edges[(y+1)*n+x]
+			}
+			if x > 0 {
+				vertices[y][x].west = edges[y*n+x-1]
+			}
+			if x < n-1 {
+				vertices[y][x].east = edges[y*n+x+1]
+			}
+			vertices[y][x].self = edges[y*n+x]
+		}
+	}
+
+	return vertices
+}
@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+// offsets are where the neighbour in each direction is
+var offsets = map[LogDirection][2]int{North: {0, -1}, South: {0, 1}, East: {1, 0}, West: {-1, 0}}
+
+func TestCreateLattice(t *testing.T) {
+	shapes := []struct {
+		name string
+		n, m int
+	}{
+		{"1x1", 1, 1},
+		{"1xk", 1, 5},
+		{"kx1", 5, 1},
+		{"square", 4, 4},
+		{"wide", 6, 3},
+		{"tall", 3, 6},
+	}
+
+	for _, shape := range shapes {
+		t.Run(shape.name, func(t *testing.T) {
+			vertices := CreateLattice(shape.n, shape.m)
+			if len(vertices) != shape.m {
+				t.Fatalf("got %d rows, want %d", len(vertices), shape.m)
+			}
+
+			// every vertex listens on its own channel, so the channel tells whose it is
+			owner := make(map[uintptr]int)
+			for y := range vertices {
+				if len(vertices[y]) != shape.n {
+					t.Fatalf("row %d has %d vertices, want %d", y, len(vertices[y]), shape.n)
+				}
+				for x := range vertices[y] {
+					v := &vertices[y][x]
+					if v.x != x || v.y != y || v.id != y*shape.n+x {
+						t.Errorf("vertex at (%d,%d) has x=%d y=%d id=%d", x, y, v.x, v.y, v.id)
+					}
+					owner[reflect.ValueOf(v.self).Pointer()] = v.id
+				}
+			}
+
+			for y := range vertices {
+				for x := range vertices[y] {
+					v := &vertices[y][x]
+					sides := []struct {
+						direction LogDirection
+						channel   chan<- *Explorer
+					}{
+						{North, v.north},
+						{South, v.south},
+						{East, v.east},
+						{West, v.west},
+					}
+					for _, side := range sides {
+						nx, ny := x+offsets[side.direction][0], y+offsets[side.direction][1]
+						outside := nx < 0 || nx >= shape.n || ny < 0 || ny >= shape.m
+						if outside {
+							if side.channel != nil {
+								t.Errorf("vertex (%d,%d) has a neighbour to the %s outside of the lattice", x, y, side.direction)
+							}
+							continue
+						}
+						if side.channel == nil {
+							t.Errorf("vertex (%d,%d) has no neighbour to the %s", x, y, side.direction)
+							continue
+						}
+						id, ok := owner[reflect.ValueOf(side.channel).Pointer()]
+						if want := ny*shape.n + nx; !ok || id != want {
+							t.Errorf("vertex (%d,%d) to the %s leads to %d, want %d", x, y, side.direction, id, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+type LogType int
+
+type LogDirection int
+
+func (l LogDirection) String() string {
+	switch l {
+	case None:
+		return ""
+	case North:
+		return "N"
+	case South:
+		return "S"
+	case East:
+		return "E"
+	case West:
+		return "W"
+	default:
+		return "No such direction"
+	}
+}
+
+type VertexLogger struct {
+	vert *Vertex
+	// occupancy says how many explorers the vertex has now
+	occupancy func() int
+	logQueue  *LogQueue
+}
+
+func (v *Vertex) CreateLogger(logQueue *LogQueue) VertexLogger {
+	occupancy := func() int {
+		return len(v.explorers)
+	}
+	return VertexLogger{vert: v, occupancy: occupancy, logQueue: logQueue}
+}
+
+func (l VertexLogger) LogExplorerSpawned(expId int) {
+	l.logQueue.Push(l.withOccupancy(MakeLogExplorerSpawned(l.vert.id, l.vert.x, l.vert.y, expId)))
+}
+
+func (l VertexLogger) LogExplorerSend(expId int, direction LogDirection) {
+	switch direction {
+	case North:
+		l.logQueue.Push(l.withOccupancy(MakeLogExplorerSend(l.vert.id, l.vert.x, l.vert.y, l.vert.x, l.vert.y-1, expId, direction)))
+	case South:
+		l.logQueue.Push(l.withOccupancy(MakeLogExplorerSend(l.vert.id, l.vert.x, l.vert.y, l.vert.x, l.vert.y+1, expId, direction)))
+	case East:
+		l.logQueue.Push(l.withOccupancy(MakeLogExplorerSend(l.vert.id, l.vert.x, l.vert.y, l.vert.x+1, l.vert.y, expId, direction)))
+	case West:
+		l.logQueue.Push(l.withOccupancy(MakeLogExplorerSend(l.vert.id, l.vert.x, l.vert.y, l.vert.x-1, l.vert.y, expId, direction)))
+	default:
+		panic("Can't log explorer send with no direction")
+	}
+}
+
+// LogExplorerEntered logs an explorer that came into the lattice from the
+// outside, direction is the side of the vertex it came through
+func (l VertexLogger) LogExplorerEntered(expId int, direction LogDirection) {
+	fromX, fromY := step(l.vert.x, l.vert.y, direction)
+	l.logQueue.Push(l.withOccupancy(MakeLogExplorerEntered(l.vert.id, fromX, fromY, l.vert.x, l.vert.y, expId, direction)))
+}
+
+// LogExplorerExited logs an explorer that left the lattice through the side
+// of the vertex in the direction
+func (l VertexLogger) LogExplorerExited(expId int, direction LogDirection) {
+	toX, toY := step(l.vert.x, l.vert.y, direction)
+	l.logQueue.Push(l.withOccupancy(MakeLogExplorerExited(l.vert.id, l.vert.x, l.vert.y, toX, toY, expId, direction)))
+}
+
+func (l VertexLogger) LogExplorerDespawned(expId int) {
+	l.logQueue.Push(l.withOccupancy(MakeLogExplorerDespawned(l.vert.id, l.vert.x, l.vert.y, expId)))
+}
+
+func (l VertexLogger) LogExplorerReceived(expId int) {
+	l.logQueue.Push(l.withOccupancy(MakeLogExplorerReceived(l.vert.id, l.vert.x, l.vert.y, expId)))
+}
+
+// withOccupancy adds to the payload how many explorers the vertex has now
+func (l VertexLogger) withOccupancy(payload LogPayload) LogPayload {
+	payload.occupancy = l.occupancy()
+	payload.capacity = l.vert.capacity
+	return payload
+}
+
+type LogPayload struct {
+	vertexId  int
+	logType   LogType
+	direction LogDirection
+	fromX     int
+	fromY     int
+	toX       int
+	toY       int
+	expId     int
+	occupancy int
+	capacity  int
+	timestamp time.Time
+	// lost counts the events by type an EventsLost marker stands for
+	lost map[LogType]int
+}
+
+const (
+	None LogDirection = iota
+	North
+	South
+	East
+	West
+)
+
+func (l LogPayload) String() string {
+	result := fmt.Sprintf("ID: %2d [%s] ", l.vertexId, l.timestamp.Format(time.StampMicro))
+	switch l.logType {
+	case ExplorerSpawned:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d)", l.expId, "spawned at", l.fromY, l.fromX)
+	case ExplorerSend:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d) %2s (%2d,%2d) [%s]", l.expId, "send from", l.fromY, l.fromX, "to", l.toY, l.toX, l.direction)
+	case ExplorerReceived:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d)", l.expId, "recived at", l.toY, l.toX)
+	case ExplorerEntered:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d) [%s]", l.expId, "entered at", l.toY, l.toX, l.direction)
+	case ExplorerExited:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d) [%s]", l.expId, "exited from", l.fromY, l.fromX, l.direction)
+	case ExplorerDespawned:
+		result += fmt.Sprintf("E-ID: %2d %12s (%2d,%2d)", l.expId, "despawned at", l.fromY, l.fromX)
+	case EventsLost:
+		total := 0
+		for _, count := range l.lost {
+			total += count
+		}
+		result += fmt.Sprintf("LOST: %d events (%s)", total, describeLost(l.lost))
+	default:
+		result += fmt.Sprint("No such log type")
+	}
+	if l.capacity > 1 {
+		// vertices that fit more than one explorer say how full they are
+		result += fmt.Sprintf(" [%d/%d]", l.occupancy, l.capacity)
+	}
+	return result
+}
+
+const (
+	ExplorerSpawned LogType = iota
+	ExplorerSend
+	ExplorerReceived
+	EventsLost
+	ExplorerEntered
+	ExplorerExited
+	ExplorerDespawned
+)
+
+func MakeLogExplorerSpawned(vertId, x, y, expId int) LogPayload {
+	return LogPayload{logType: ExplorerSpawned, fromX: x, fromY: y, expId: expId, timestamp: time.Now(), direction: None, vertexId: vertId}
+}
+
+func MakeLogExplorerSend(vertId, fromX, fromY, toX, toY, expId int, direction LogDirection) LogPayload {
+	return LogPayload{logType: ExplorerSend, fromX: fromX, fromY: fromY, toX: toX, toY: toY, expId: expId, timestamp: time.Now(), direction: direction, vertexId: vertId}
+}
+
+func MakeLogExplorerReceived(vertId, atX, atY, expId int) LogPayload {
+	return LogPayload{logType: ExplorerReceived, toX: atX, toY: atY, expId: expId, timestamp: time.Now(), direction: None, vertexId: vertId}
+}
+
+func MakeLogExplorerEntered(vertId, fromX, fromY, toX, toY, expId int, direction LogDirection) LogPayload {
+	return LogPayload{logType: ExplorerEntered, fromX: fromX, fromY: fromY, toX: toX, toY: toY, expId: expId, timestamp: time.Now(), direction: direction, vertexId: vertId}
+}
+
+func MakeLogExplorerExited(vertId, fromX, fromY, toX, toY, expId int, direction LogDirection) LogPayload {
+	return LogPayload{logType: ExplorerExited, fromX: fromX, fromY: fromY, toX: toX, toY: toY, expId: expId, timestamp: time.Now(), direction: direction, vertexId: vertId}
+}
+
+func MakeLogExplorerDespawned(vertId, x, y, expId int) LogPayload {
+	return LogPayload{logType: ExplorerDespawned, fromX: x, fromY: y, expId: expId, timestamp: time.Now(), direction: None, vertexId: vertId}
+}
+
+func MakeLogEventsLost(lost map[LogType]int) LogPayload {
+	return LogPayload{logType: EventsLost, lost: lost, timestamp: time.Now(), direction: None, vertexId: -1}
+}
+
+func loggerRun(logChanel <-chan LogPayload, cameraChanel chan<- CameraMessage, logConfig LogConfig, trajectories *Trajectories, tracker *positionTracker, events *eventHub) {
+	sinks := openLogSinks(logConfig)
+	defer func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}()
+
+	for log := range logChanel {
+		if logConfig.wants(log.logType) {
+			line := log.String() + "\n"
+			for _, sink := range sinks {
+				sink.WriteLine(line)
+			}
+		}
+
+		trajectories.Record(log)
+		tracker.Record(log)
+		events.Publish(log)
+
+		switch log.logType {
+		case ExplorerSpawned:
+			cameraChanel <- RecordSpawnExplorer(log.expId, log.fromX, log.fromY)
+		case ExplorerSend:
+			cameraChanel <- RecordMoveExplorer(log.expId, log.fromX, log.fromY, log.toX, log.toY)
+		case ExplorerEntered:
+			cameraChanel <- RecordEnterExplorer(log.expId, log.fromX, log.fromY, log.toX, log.toY)
+		case ExplorerExited:
+			cameraChanel <- RecordExitExplorer(log.expId, log.fromX, log.fromY, log.toX, log.toY)
+		case ExplorerDespawned:
+			cameraChanel <- RecordRemoveExplorer(log.expId, log.fromX, log.fromY)
+		}
+	}
+
+	close(cameraChanel)
+}
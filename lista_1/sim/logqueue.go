@@ -0,0 +1,217 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OverflowPolicy says what a full LogQueue does with one more event
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the routine that logs wait for a free slot
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest throws away the oldest waiting event
+	OverflowDropOldest
+	// OverflowDropNewest throws away the event that didn't fit
+	OverflowDropNewest
+)
+
+var overflowPolicyNames = map[string]OverflowPolicy{
+	"block":       OverflowBlock,
+	"drop-oldest": OverflowDropOldest,
+	"drop-newest": OverflowDropNewest,
+}
+
+func (p OverflowPolicy) String() string {
+	for name, policy := range overflowPolicyNames {
+		if policy == p {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func parseOverflowPolicy(name string) (OverflowPolicy, error) {
+	policy, ok := overflowPolicyNames[name]
+	if !ok {
+		return OverflowBlock, fmt.Errorf("unknown log overflow policy %q, use block, drop-oldest or drop-newest", name)
+	}
+	return policy, nil
+}
+
+// LogQueue is a bounded ring of log events between the routines of the
+// simulation and the logger. Unless the policy is block, Push never waits:
+// events that don't fit are counted per type and a marker saying how many
+// were lost takes their place in the stream.
+type LogQueue struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	ring     []LogPayload
+	head     int
+	count    int
+	// next is the sequence number the next accepted event gets
+	next    uint64
+	policy  OverflowPolicy
+	closed  bool
+	gaps    []logGap
+	dropped map[LogType]int
+}
+
+// logGap is a run of lost events, its marker goes right before the event
+// with the sequence number before
+type logGap struct {
+	before uint64
+	lost   map[LogType]int
+}
+
+func NewLogQueue(size int, policy OverflowPolicy) *LogQueue {
+	if size < 1 {
+		size = 1
+	}
+	q := &LogQueue{ring: make([]LogPayload, size), policy: policy, dropped: make(map[LogType]int)}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *LogQueue) Push(msg LogPayload) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.count == len(q.ring) && !q.closed {
+		switch q.policy {
+		case OverflowDropNewest:
+			q.lose(msg, q.next, q.next)
+			return
+		case OverflowDropOldest:
+			oldest := q.next - uint64(q.count)
+			q.lose(q.ring[q.head], oldest, oldest+1)
+			q.ring[q.head] = LogPayload{}
+			q.head = (q.head + 1) % len(q.ring)
+			q.count -= 1
+		default:
+			q.notFull.Wait()
+		}
+	}
+	if q.closed {
+		// nobody reads the queue any more
+		q.lose(msg, q.next, q.next)
+		return
+	}
+
+	q.ring[(q.head+q.count)%len(q.ring)] = msg
+	q.count += 1
+	q.next += 1
+	q.notEmpty.Signal()
+}
+
+// lose counts a lost event. A gap that ends right before the event at from
+// grows to end before the event at before, otherwise a new gap starts.
+// A lost marker isn't counted itself, the events it stood for go to the gap.
+func (q *LogQueue) lose(msg LogPayload, from, before uint64) {
+	lost := map[LogType]int{msg.logType: 1}
+	if msg.logType == EventsLost {
+		lost = msg.lost
+	} else {
+		q.dropped[msg.logType] += 1
+	}
+
+	last := len(q.gaps) - 1
+	if last < 0 || q.gaps[last].before != from {
+		q.gaps = append(q.gaps, logGap{before: before, lost: make(map[LogType]int)})
+		last += 1
+	}
+	q.gaps[last].before = before
+	for logType, count := range lost {
+		q.gaps[last].lost[logType] += count
+	}
+}
+
+// pop waits for the next event or marker, it returns false once the queue
+// is closed and empty
+func (q *LogQueue) pop() (LogPayload, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.count == 0 && len(q.gaps) == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if len(q.gaps) > 0 && (q.count == 0 || q.gaps[0].before <= q.next-uint64(q.count)) {
+		gap := q.gaps[0]
+		q.gaps = q.gaps[1:]
+		return MakeLogEventsLost(gap.lost), true
+	}
+	if q.count == 0 {
+		return LogPayload{}, false
+	}
+
+	msg := q.ring[q.head]
+	q.ring[q.head] = LogPayload{}
+	q.head = (q.head + 1) % len(q.ring)
+	q.count -= 1
+	q.notFull.Signal()
+	return msg, true
+}
+
+// Messages passes the queued events on to the returned channel, which is
+// closed after the queue is closed and drained
+func (q *LogQueue) Messages() <-chan LogPayload {
+	out := make(chan LogPayload)
+	go func() {
+		for {
+			msg, ok := q.pop()
+			if !ok {
+				break
+			}
+			out <- msg
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Close lets the reader finish once everything queued so far is read
+func (q *LogQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// ReportDropped warns about the events lost over the whole run
+func (q *LogQueue) ReportDropped() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	total := 0
+	for _, count := range q.dropped {
+		total += count
+	}
+	if total > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: lost %d log events: %s\n", total, describeLost(q.dropped))
+	}
+}
+
+// describeLost lists the lost events by type, the most common first
+func describeLost(lost map[LogType]int) string {
+	types := make([]LogType, 0, len(lost))
+	for logType := range lost {
+		types = append(types, logType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if lost[types[i]] != lost[types[j]] {
+			return lost[types[i]] > lost[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	parts := make([]string, len(types))
+	for i, logType := range types {
+		parts[i] = fmt.Sprintf("%s %d", logTypeName(logType), lost[logType])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,223 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// PopulationMode says how the number of explorers on the lattice is kept
+type PopulationMode int
+
+const (
+	// Saturate spawns explorers until the lattice is full
+	Saturate PopulationMode = iota
+	// Fixed places the explorers at the start, none are born or removed later
+	Fixed
+	// Density spawns and removes explorers to keep a target density
+	Density
+	// Schedule is Density with a target that changes over time
+	Schedule
+)
+
+// ScheduleStep is the target density from the time At after the start, the
+// target goes linearly from one step to the next
+type ScheduleStep struct {
+	At      time.Duration
+	Density float64
+}
+
+// Population hands out the explorer ids and counts the explorers on the
+// lattice, explorers that leave an open lattice make room for new ones
+type Population struct {
+	lastId   atomic.Uint64
+	alive    atomic.Int64
+	max      int64
+	vertices int
+	mode     PopulationMode
+	count    int64
+	density  float64
+	schedule []ScheduleStep
+	start    time.Time
+}
+
+func NewPopulation(mode PopulationMode, max int64, vertices int) *Population {
+	return &Population{mode: mode, max: max, vertices: vertices, start: time.Now()}
+}
+
+// parsePopulation reads the population flags, schedulePath is only read
+// for the schedule mode
+func parsePopulation(name string, count int, density float64, schedulePath string, max int64, vertices int) (*Population, error) {
+	var p *Population
+	switch name {
+	case "saturate":
+		p = NewPopulation(Saturate, max, vertices)
+	case "fixed":
+		if count < 0 || int64(count) > max {
+			return nil, fmt.Errorf("a fixed population has to be between 0 and %d explorers", max)
+		}
+		p = NewPopulation(Fixed, max, vertices)
+		p.count = int64(count)
+	case "density":
+		if density < 0 || density > 1 {
+			return nil, fmt.Errorf("the target density has to be between 0 and 1")
+		}
+		p = NewPopulation(Density, max, vertices)
+		p.density = density
+	case "schedule":
+		schedule, err := readSchedule(schedulePath)
+		if err != nil {
+			return nil, err
+		}
+		p = NewPopulation(Schedule, max, vertices)
+		p.schedule = schedule
+	default:
+		return nil, fmt.Errorf("unknown population mode %q, use saturate, fixed, density or schedule", name)
+	}
+	return p, nil
+}
+
+// readSchedule reads a json list like [{"at": "0s", "density": 0.1}, ...]
+func readSchedule(path string) ([]ScheduleStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the schedule population needs a schedule file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []struct {
+		At      string  `json:"at"`
+		Density float64 `json:"density"`
+	}
+	err = json.Unmarshal(data, &steps)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", path, err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("schedule %s has no steps", path)
+	}
+
+	schedule := make([]ScheduleStep, len(steps))
+	for i, step := range steps {
+		at, err := time.ParseDuration(step.At)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", path, err)
+		}
+		if at < 0 || step.Density < 0 || step.Density > 1 {
+			return nil, fmt.Errorf("schedule %s: step %d needs a time from the start and a density between 0 and 1", path, i)
+		}
+		schedule[i] = ScheduleStep{At: at, Density: step.Density}
+	}
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].At < schedule[j].At
+	})
+	return schedule, nil
+}
+
+// densityAt is the scheduled density after elapsed time
+func (p *Population) densityAt(elapsed time.Duration) float64 {
+	steps := p.schedule
+	if elapsed <= steps[0].At {
+		return steps[0].Density
+	}
+	for i := 1; i < len(steps); i++ {
+		if elapsed < steps[i].At {
+			part := float64(elapsed-steps[i-1].At) / float64(steps[i].At-steps[i-1].At)
+			return steps[i-1].Density + part*(steps[i].Density-steps[i-1].Density)
+		}
+	}
+	return steps[len(steps)-1].Density
+}
+
+// target is how many explorers the lattice should hold now
+func (p *Population) target() int64 {
+	var target int64
+	switch p.mode {
+	case Fixed:
+		target = p.count
+	case Density:
+		target = int64(math.Round(p.density * float64(p.vertices)))
+	case Schedule:
+		target = int64(math.Round(p.densityAt(time.Since(p.start)) * float64(p.vertices)))
+	default:
+		target = p.max
+	}
+	if target > p.max {
+		target = p.max
+	}
+	return target
+}
+
+// spawnChance is the chance that a free vertex spawns an explorer on a spawn
+// tick, it gets smaller as the population gets closer to the target
+func (p *Population) spawnChance() float64 {
+	switch p.mode {
+	case Saturate:
+		return spawnExplorerRate
+	case Fixed:
+		return 0
+	}
+	target := p.target()
+	alive := p.alive.Load()
+	if alive >= target {
+		return 0
+	}
+	return spawnExplorerRate * float64(target-alive) / float64(target)
+}
+
+// despawnChance is the chance that an explorer is removed on a move tick, it
+// is only above zero while there are more explorers than the target
+func (p *Population) despawnChance() float64 {
+	if p.mode != Density && p.mode != Schedule {
+		return 0
+	}
+	target := p.target()
+	alive := p.alive.Load()
+	if alive <= target {
+		return 0
+	}
+	return despawnExplorerRate * float64(alive-target) / float64(alive)
+}
+
+// admit makes room for one more explorer and returns its id, it fails when
+// the lattice already holds as many explorers as it should
+func (p *Population) admit() (int, bool) {
+	for {
+		alive := p.alive.Load()
+		if alive >= p.target() {
+			return 0, false
+		}
+		if p.alive.CompareAndSwap(alive, alive+1) {
+			return int(p.lastId.Add(1)), true
+		}
+	}
+}
+
+func (p *Population) leave() {
+	p.alive.Add(-1)
+}
+
+// placeFixed puts the explorers of a fixed population on random vertices
+// that have room for them, before the vertices start running
+func (p *Population) placeFixed(vertices [][]Vertex, logQueue *LogQueue) {
+	if p.mode != Fixed {
+		return
+	}
+	m := len(vertices)
+	n := len(vertices[0])
+	for p.alive.Load() < p.count {
+		v := &vertices[rand.Intn(m)][rand.Intn(n)]
+		if len(v.explorers) >= v.capacity {
+			continue
+		}
+		id, _ := p.admit()
+		v.explorers = append(v.explorers, &Explorer{id: id})
+		v.CreateLogger(logQueue).LogExplorerSpawned(id)
+	}
+}
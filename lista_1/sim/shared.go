@@ -0,0 +1,194 @@
+package sim
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sharedCell is a vertex of the shared grid backend. The explorers are ids in
+// slots, 0 is an empty slot. occupied counts the taken and the reserved
+// slots and only grows with compare-and-swap, so a cell never holds more
+// explorers than its capacity. Only the routine of the cell takes explorers
+// out of its slots, the neighbours only put them in.
+type sharedCell struct {
+	vertex   *Vertex
+	occupied atomic.Int32
+	slots    []atomic.Int64
+	logger   VertexLogger
+}
+
+// sharedGrid is the lattice of the shared backend, it keeps the vertices of
+// CreateLattice for their position and capacity but not for their channels
+type sharedGrid struct {
+	n     int
+	m     int
+	cells [][]*sharedCell
+}
+
+func newSharedGrid(vertices [][]Vertex, logQueue *LogQueue) *sharedGrid {
+	m := len(vertices)
+	n := len(vertices[0])
+	grid := &sharedGrid{n: n, m: m, cells: make([][]*sharedCell, m)}
+	for y := 0; y < m; y++ {
+		grid.cells[y] = make([]*sharedCell, n)
+		for x := 0; x < n; x++ {
+			v := &vertices[y][x]
+			c := &sharedCell{vertex: v, slots: make([]atomic.Int64, v.capacity)}
+			c.logger = VertexLogger{vert: v, occupancy: c.count, logQueue: logQueue}
+			// explorers placed before the start move over to the slots
+			for i, e := range v.explorers {
+				c.slots[i].Store(int64(e.id))
+			}
+			c.occupied.Store(int32(len(v.explorers)))
+			grid.cells[y][x] = c
+		}
+	}
+	return grid
+}
+
+func (c *sharedCell) count() int {
+	return int(c.occupied.Load())
+}
+
+// reserve takes one slot of the cell if there is a free one
+func (c *sharedCell) reserve() bool {
+	for {
+		occupied := c.occupied.Load()
+		if int(occupied) >= len(c.slots) {
+			return false
+		}
+		if c.occupied.CompareAndSwap(occupied, occupied+1) {
+			return true
+		}
+	}
+}
+
+// put puts the explorer into an empty slot, the slot has to be reserved
+func (c *sharedCell) put(id int) {
+	for {
+		for i := range c.slots {
+			if c.slots[i].CompareAndSwap(0, int64(id)) {
+				return
+			}
+		}
+	}
+}
+
+// take empties the slot and gives its reservation back
+func (c *sharedCell) take(slot int) int {
+	id := c.slots[slot].Swap(0)
+	c.occupied.Add(-1)
+	return int(id)
+}
+
+// explorerSlots returns the slots holding an explorer
+func (c *sharedCell) explorerSlots() []int {
+	var taken []int
+	for i := range c.slots {
+		if c.slots[i].Load() != 0 {
+			taken = append(taken, i)
+		}
+	}
+	return taken
+}
+
+// startShared runs every cell of a shared grid in its own routine, explorers
+// are moved by the routine of the cell they leave
+func startShared(vertices [][]Vertex, params *runParams, wg *sync.WaitGroup) {
+	grid := newSharedGrid(vertices, params.logQueue)
+	for y := range grid.cells {
+		for x := range grid.cells[y] {
+			wg.Add(1)
+			go func(c *sharedCell) {
+				sharedRunner(grid, c, params)
+				wg.Done()
+			}(grid.cells[y][x])
+		}
+	}
+}
+
+// sharedRunner does what runner does for a vertex, on the shared grid
+func sharedRunner(grid *sharedGrid, c *sharedCell, params *runParams) {
+	v := c.vertex
+	sides := v.openSides()
+	population := params.population
+	boundary := params.boundary
+	logger := c.logger
+
+	tick := params.moveTick
+	if params.spawnTick < tick {
+		tick = params.spawnTick
+	}
+
+	for !params.quit.Load() {
+		time.Sleep(tick)
+
+		// like the select of runner, a step either spawns or explores
+		taken := c.explorerSlots()
+		canSpawn := c.count() < len(c.slots)
+		spawn := canSpawn && (len(taken) == 0 || rand.Intn(2) == 0)
+
+		if spawn {
+			if boundary == nil {
+				if rand.Float64() < population.spawnChance() && c.reserve() {
+					id, ok := population.admit()
+					if ok {
+						c.put(id)
+						logger.LogExplorerSpawned(id)
+					} else {
+						c.occupied.Add(-1)
+					}
+				}
+			} else if len(sides) > 0 && rand.Float64() < boundary.arrivalRate && c.reserve() {
+				id, ok := population.admit()
+				if ok {
+					c.put(id)
+					logger.LogExplorerEntered(id, sides[rand.Intn(len(sides))])
+				} else {
+					c.occupied.Add(-1)
+				}
+			}
+			continue
+		}
+		if len(taken) == 0 {
+			continue
+		}
+
+		slot := taken[rand.Intn(len(taken))]
+		if rand.Float64() < population.despawnChance() {
+			id := c.take(slot)
+			population.leave()
+			logger.LogExplorerDespawned(id)
+			continue
+		}
+		if rand.Float64() >= moveExplorerRate {
+			continue
+		}
+		if boundary != nil && len(sides) > 0 && rand.Float64() < boundary.exitRate {
+			id := c.take(slot)
+			population.leave()
+			logger.LogExplorerExited(id, sides[rand.Intn(len(sides))])
+			continue
+		}
+
+		// try the neighbours in random order like a select does
+		for _, i := range rand.Perm(4) {
+			direction := []LogDirection{North, South, East, West}[i]
+			x, y := step(v.x, v.y, direction)
+			if x < 0 || x >= grid.n || y < 0 || y >= grid.m || v.blocked[direction] {
+				continue
+			}
+			neighbour := grid.cells[y][x]
+			if !neighbour.reserve() {
+				continue
+			}
+			id := c.take(slot)
+			neighbour.put(id)
+			logger.LogExplorerSend(id, direction)
+			neighbour.logger.LogExplorerReceived(id)
+			break
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package sim is the explorer lattice simulation. The command line program is
+// a thin wrapper around it, other programs can run it with New and Run and
+// follow it with Subscribe and Snapshot.
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	spawnExplorerTick   = 50 * time.Millisecond
+	moveExplorerTick    = 50 * time.Millisecond
+	spawnExplorerRate   = 0.01
+	despawnExplorerRate = 0.05
+	moveExplorerRate    = 0.10
+	DefaultArrivalRate  = 0.05
+	DefaultExitRate     = 0.20
+	DefaultLogBuffer    = 100
+	runTime             = 10 * time.Second
+	cameraTick          = 300 * time.Millisecond
+	cameraBuffer        = 100
+	benchTick           = time.Millisecond
+	benchSample         = 10 * time.Millisecond
+	subscriberBuffer    = 1000
+)
+
+// Options are the settings of a run. The command line fills them from its
+// flags, a program embedding the simulation fills them itself.
+type Options struct {
+	// N is the width and M the height of the lattice
+	N int
+	M int
+	// Capacity is how many explorers fit on one vertex, 0 is 1, and
+	// CapacityMap overrides it for single vertices
+	Capacity    int
+	CapacityMap []CapacityConfig
+	// Open lets explorers leave through the edges of the lattice and arrive
+	// there, ArrivalRate and ExitRate both 0 take the defaults
+	Open        bool
+	ArrivalRate float64
+	ExitRate    float64
+	// Population is saturate, fixed, density or schedule, empty is saturate.
+	// PopulationCount is for fixed, Density for density and SchedulePath for
+	// schedule.
+	Population      string
+	PopulationCount int
+	Density         float64
+	SchedulePath    string
+	// Drift makes every edge along it one way, it is N, S, E or W, and
+	// OneWay makes single edges one way
+	Drift  string
+	OneWay []EdgeConfig
+	// Backend is channels, shared or travellers, empty is channels
+	Backend string
+	// Log says where the log goes, the zero value writes no log
+	Log LogConfig
+	// RunTime is how long Run runs unless its context ends first, 0 is the
+	// default of the command line
+	RunTime time.Duration
+	// Output gets the camera and the progress messages, nil runs without them
+	Output io.Writer
+	// Trail is how many moves of every explorer the camera draws
+	Trail int
+	// TrajectoriesPath is the prefix the trajectories are exported to at the
+	// end of the run, empty exports nothing
+	TrajectoriesPath string
+}
+
+// withDefaults fills the options left at their zero value
+func (o Options) withDefaults() Options {
+	if o.Capacity == 0 {
+		o.Capacity = 1
+	}
+	if o.ArrivalRate == 0 && o.ExitRate == 0 {
+		o.ArrivalRate, o.ExitRate = DefaultArrivalRate, DefaultExitRate
+	}
+	if o.Population == "" {
+		o.Population = "saturate"
+	}
+	if o.Backend == "" {
+		o.Backend = "channels"
+	}
+	if o.RunTime == 0 {
+		o.RunTime = runTime
+	}
+	if o.Log.Buffer == 0 {
+		o.Log.Buffer = DefaultLogBuffer
+	}
+	return o
+}
+
+// ExplorerPosition is where one explorer stands
+type ExplorerPosition struct {
+	Id int `json:"id"`
+	X  int `json:"x"`
+	Y  int `json:"y"`
+}
+
+// Snapshot is the state of the lattice as far as the logger has seen it
+type Snapshot struct {
+	Time      time.Time          `json:"time"`
+	N         int                `json:"n"`
+	M         int                `json:"m"`
+	Explorers []ExplorerPosition `json:"explorers"`
+}
+
+// positionTracker follows the explorers through the log, the logger writes
+// to it and Snapshot reads it
+type positionTracker struct {
+	mu        sync.Mutex
+	explorers map[int]ExplorerPosition
+}
+
+func newPositionTracker() *positionTracker {
+	return &positionTracker{explorers: make(map[int]ExplorerPosition)}
+}
+
+func (t *positionTracker) Record(log LogPayload) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch log.logType {
+	case ExplorerSpawned:
+		t.explorers[log.expId] = ExplorerPosition{Id: log.expId, X: log.fromX, Y: log.fromY}
+	case ExplorerSend, ExplorerEntered:
+		t.explorers[log.expId] = ExplorerPosition{Id: log.expId, X: log.toX, Y: log.toY}
+	case ExplorerExited, ExplorerDespawned:
+		delete(t.explorers, log.expId)
+	}
+}
+
+func (t *positionTracker) positions() []ExplorerPosition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]ExplorerPosition, 0, len(t.explorers))
+	for _, position := range t.explorers {
+		list = append(list, position)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// Simulation is one run of the lattice
+type Simulation struct {
+	opts       Options
+	vertices   [][]Vertex
+	population *Population
+	boundary   *OpenBoundary
+	tracker    *positionTracker
+	events     *eventHub
+	started    atomic.Bool
+}
+
+// New builds the lattice of a run, nothing moves until Run is called
+func New(opts Options) (*Simulation, error) {
+	opts = opts.withDefaults()
+	if opts.N < 1 || opts.M < 1 {
+		return nil, errors.New("lattice dimensions must be positive")
+	}
+	if opts.Trail < 0 {
+		return nil, errors.New("the trail can't be negative")
+	}
+	if _, ok := backends[opts.Backend]; !ok {
+		return nil, fmt.Errorf("unknown backend %q, use channels, shared or travellers", opts.Backend)
+	}
+
+	s := &Simulation{
+		opts:     opts,
+		vertices: CreateLattice(opts.N, opts.M),
+		tracker:  newPositionTracker(),
+		events:   newEventHub(),
+	}
+	maxExplorers, err := SetCapacities(s.vertices, opts.Capacity, opts.CapacityMap)
+	if err != nil {
+		return nil, err
+	}
+	err = SetEdges(s.vertices, opts.Drift, opts.OneWay)
+	if err != nil {
+		return nil, err
+	}
+	// one vertex is left free, so explorers always have somewhere to go
+	s.population, err = parsePopulation(opts.Population, opts.PopulationCount, opts.Density, opts.SchedulePath, int64(maxExplorers-1), opts.N*opts.M)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Open {
+		if opts.ArrivalRate < 0 || opts.ArrivalRate > 1 || opts.ExitRate < 0 || opts.ExitRate > 1 {
+			return nil, errors.New("the arrival and exit rates have to be between 0 and 1")
+		}
+		s.boundary = &OpenBoundary{arrivalRate: opts.ArrivalRate, exitRate: opts.ExitRate}
+	}
+	return s, nil
+}
+
+// Subscribe returns a channel that gets every event of the run from now on
+// and is closed when the run ends. The subscriber has to keep reading, one
+// that lets its channel fill up is dropped and its channel closed early.
+func (s *Simulation) Subscribe() <-chan Event {
+	return s.events.Subscribe()
+}
+
+// Snapshot returns where the explorers are as far as the logger has seen
+func (s *Simulation) Snapshot() Snapshot {
+	return Snapshot{Time: time.Now(), N: s.opts.N, M: s.opts.M, Explorers: s.tracker.positions()}
+}
+
+// Run runs the simulation until the run time is over or the context is done
+// and waits until every routine has stopped
+func (s *Simulation) Run(ctx context.Context) error {
+	if s.started.Swap(true) {
+		return errors.New("a simulation can only run once")
+	}
+
+	opts := s.opts
+	n, m := opts.N, opts.M
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+	quit := atomic.Bool{}
+
+	logQueue := NewLogQueue(opts.Log.Buffer, opts.Log.Overflow)
+	loggerDone := make(chan bool)
+	cameraChanel := make(chan CameraMessage, cameraBuffer)
+	cameraDone := make(chan bool)
+
+	var trajectories *Trajectories
+	if opts.TrajectoriesPath != "" {
+		trajectories = NewTrajectories()
+	}
+
+	go func() {
+		loggerRun(logQueue.Messages(), cameraChanel, opts.Log, trajectories, s.tracker, s.events)
+		loggerDone <- true
+	}()
+
+	go func() {
+		if opts.Output != nil {
+			camera := NewCamera(cameraChanel, n, m, opts.Output, opts.Trail, oneWayEdgesOf(s.vertices))
+			camera.Start()
+		} else {
+			for range cameraChanel {
+			}
+		}
+		cameraDone <- true
+	}()
+
+	// a schedule starts with the run, not with New
+	s.population.start = time.Now()
+	s.population.placeFixed(s.vertices, logQueue)
+
+	wg := sync.WaitGroup{}
+	params := &runParams{population: s.population, quit: &quit, boundary: s.boundary, logQueue: logQueue, spawnTick: spawnExplorerTick, moveTick: moveExplorerTick}
+	err := startBackend(opts.Backend, s.vertices, params, &wg)
+	if err != nil {
+		// nothing runs, but the logger and the camera have to be stopped
+		logQueue.Close()
+		<-loggerDone
+		s.events.Close()
+		<-cameraDone
+		return err
+	}
+
+	runTimer := time.NewTimer(opts.RunTime)
+	select {
+	case <-runTimer.C:
+	case <-ctx.Done():
+		runTimer.Stop()
+	}
+	quit.Store(true)
+	wg.Wait()
+	stoppedAt := time.Now()
+
+	logQueue.Close()
+
+	<-loggerDone
+	logQueue.ReportDropped()
+	s.events.Close()
+	if trajectories != nil {
+		err := trajectories.Export(opts.TrajectoriesPath, stoppedAt, output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not write the trajectories:", err)
+		}
+	}
+	<-cameraDone
+	return nil
+}
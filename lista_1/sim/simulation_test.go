@@ -0,0 +1,23 @@
+package sim
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunStopsRoutinesOnBackendError(t *testing.T) {
+	s, err := New(Options{N: 3, M: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.opts.Backend = "nowhere"
+	events := s.Subscribe()
+
+	err = s.Run(context.Background())
+	if err == nil {
+		t.Fatal("Run started an unknown backend")
+	}
+	// the logger closes the events when it is done
+	for range events {
+	}
+}
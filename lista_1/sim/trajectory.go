@@ -0,0 +1,218 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Visit is one stay of an explorer on a vertex
+type Visit struct {
+	X         int       `json:"x"`
+	Y         int       `json:"y"`
+	Arrival   time.Time `json:"arrival"`
+	Departure time.Time `json:"departure"`
+	// DwellMs is how long the explorer stayed, in milliseconds
+	DwellMs float64 `json:"dwellMs"`
+}
+
+// Trajectory is the ordered list of vertices one explorer went through, an
+// explorer that is still on the lattice at the end leaves at the end of the run
+type Trajectory struct {
+	Id     int     `json:"id"`
+	Visits []Visit `json:"visits"`
+	// Left says the explorer exited or was removed before the end of the run
+	Left bool `json:"left"`
+}
+
+// Trajectories builds the trajectory of every explorer from the log, it is
+// only used by the logger routine
+type Trajectories struct {
+	byId map[int]*Trajectory
+}
+
+func NewTrajectories() *Trajectories {
+	return &Trajectories{byId: make(map[int]*Trajectory)}
+}
+
+// Record follows the explorer of the log event, a nil recorder does nothing
+func (t *Trajectories) Record(log LogPayload) {
+	if t == nil {
+		return
+	}
+	switch log.logType {
+	case ExplorerSpawned:
+		t.arrive(log.expId, log.fromX, log.fromY, log.timestamp)
+	case ExplorerEntered:
+		t.arrive(log.expId, log.toX, log.toY, log.timestamp)
+	case ExplorerSend:
+		t.depart(log.expId, log.timestamp)
+		t.arrive(log.expId, log.toX, log.toY, log.timestamp)
+	case ExplorerExited, ExplorerDespawned:
+		t.depart(log.expId, log.timestamp)
+		if trajectory, ok := t.byId[log.expId]; ok {
+			trajectory.Left = true
+		}
+	}
+}
+
+func (t *Trajectories) arrive(id, x, y int, at time.Time) {
+	trajectory, ok := t.byId[id]
+	if !ok {
+		trajectory = &Trajectory{Id: id}
+		t.byId[id] = trajectory
+	}
+	trajectory.Visits = append(trajectory.Visits, Visit{X: x, Y: y, Arrival: at})
+}
+
+func (t *Trajectories) depart(id int, at time.Time) {
+	trajectory, ok := t.byId[id]
+	if !ok || len(trajectory.Visits) == 0 {
+		return
+	}
+	visit := &trajectory.Visits[len(trajectory.Visits)-1]
+	if visit.Departure.IsZero() {
+		visit.Departure = at
+		visit.DwellMs = float64(at.Sub(visit.Arrival)) / float64(time.Millisecond)
+	}
+}
+
+// finish lets the explorers still on the lattice leave at the end of the run
+// and returns the trajectories ordered by id
+func (t *Trajectories) finish(end time.Time) []*Trajectory {
+	list := make([]*Trajectory, 0, len(t.byId))
+	for id, trajectory := range t.byId {
+		t.depart(id, end)
+		list = append(list, trajectory)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// positionAt is where the explorer was at the given time, ok is false when
+// it wasn't on the lattice
+func (tr *Trajectory) positionAt(at time.Time) (x, y int, ok bool) {
+	for _, visit := range tr.Visits {
+		if !at.Before(visit.Arrival) && at.Before(visit.Departure) {
+			return visit.X, visit.Y, true
+		}
+	}
+	return 0, 0, false
+}
+
+// MsdPoint is the mean squared displacement of the explorers Lag after they
+// appeared, Explorers is how many of them were still on the lattice then
+type MsdPoint struct {
+	Lag       time.Duration
+	Msd       float64
+	Explorers int
+}
+
+// meanSquaredDisplacement samples the squared distance from the first vertex
+// of every explorer every step after it appeared
+func meanSquaredDisplacement(trajectories []*Trajectory, step time.Duration) []MsdPoint {
+	var points []MsdPoint
+	for lag := time.Duration(0); ; lag += step {
+		sum := 0.0
+		count := 0
+		for _, tr := range trajectories {
+			if len(tr.Visits) == 0 {
+				continue
+			}
+			start := tr.Visits[0]
+			x, y, ok := tr.positionAt(start.Arrival.Add(lag))
+			if !ok {
+				continue
+			}
+			dx, dy := float64(x-start.X), float64(y-start.Y)
+			sum += dx*dx + dy*dy
+			count += 1
+		}
+		if count == 0 {
+			return points
+		}
+		points = append(points, MsdPoint{Lag: lag, Msd: sum / float64(count), Explorers: count})
+	}
+}
+
+// Export writes prefix.csv and prefix.json with the trajectories and
+// prefix-msd.csv with the mean squared displacement, then prints a summary
+func (t *Trajectories) Export(prefix string, end time.Time, out io.Writer) error {
+	trajectories := t.finish(end)
+
+	err := writeFile(prefix+".csv", func(w io.Writer) error {
+		fmt.Fprintln(w, "explorer,step,x,y,arrival,departure,dwell_ms")
+		for _, tr := range trajectories {
+			for i, v := range tr.Visits {
+				fmt.Fprintf(w, "%d,%d,%d,%d,%s,%s,%.3f\n", tr.Id, i, v.X, v.Y,
+					v.Arrival.Format(time.RFC3339Nano), v.Departure.Format(time.RFC3339Nano), v.DwellMs)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(prefix+".json", func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(trajectories)
+	})
+	if err != nil {
+		return err
+	}
+
+	msd := meanSquaredDisplacement(trajectories, cameraTick)
+	err = writeFile(prefix+"-msd.csv", func(w io.Writer) error {
+		fmt.Fprintln(w, "lag_s,msd,explorers")
+		for _, p := range msd {
+			fmt.Fprintf(w, "%.3f,%.4f,%d\n", p.Lag.Seconds(), p.Msd, p.Explorers)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	visits := 0
+	dwell := 0.0
+	for _, tr := range trajectories {
+		visits += len(tr.Visits)
+		for _, v := range tr.Visits {
+			dwell += v.DwellMs
+		}
+	}
+	fmt.Fprintf(out, "INFO: wrote %d trajectories with %d visits to %s.csv and %s.json\n", len(trajectories), visits, prefix, prefix)
+	if visits > 0 {
+		fmt.Fprintf(out, "INFO: mean dwell time %.1fms, mean path %.1f vertices\n", dwell/float64(visits), float64(visits)/float64(len(trajectories)))
+	}
+	if len(msd) > 0 {
+		last := msd[len(msd)-1]
+		fmt.Fprintf(out, "INFO: mean squared displacement %.2f after %s, see %s-msd.csv\n", last.Msd, last.Lag, prefix)
+	}
+	return nil
+}
+
+// writeFile creates the file and writes it through a buffer
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,167 @@
+package sim
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// passiveVertex is a vertex of the travellers backend. It runs no routine of
+// its own, an explorer holds one of its slots while it stays on it.
+type passiveVertex struct {
+	vertex *Vertex
+	slots  chan struct{}
+	logger VertexLogger
+}
+
+func (p *passiveVertex) acquire() bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *passiveVertex) release() {
+	<-p.slots
+}
+
+// perExplorer splits a chance per vertex and tick between the explorers on
+// the vertex. The other backends roll once for the whole vertex, here every
+// explorer rolls for itself, so a vertex still loses as many explorers per
+// tick on average.
+func (p *passiveVertex) perExplorer(chance float64) float64 {
+	return chance / float64(len(p.slots))
+}
+
+// passiveGrid is the lattice of the travellers backend
+type passiveGrid struct {
+	n        int
+	m        int
+	vertices [][]*passiveVertex
+}
+
+func newPassiveGrid(vertices [][]Vertex, logQueue *LogQueue) *passiveGrid {
+	m := len(vertices)
+	n := len(vertices[0])
+	grid := &passiveGrid{n: n, m: m, vertices: make([][]*passiveVertex, m)}
+	for y := 0; y < m; y++ {
+		grid.vertices[y] = make([]*passiveVertex, n)
+		for x := 0; x < n; x++ {
+			v := &vertices[y][x]
+			p := &passiveVertex{vertex: v, slots: make(chan struct{}, v.capacity)}
+			occupancy := func() int {
+				return len(p.slots)
+			}
+			p.logger = VertexLogger{vert: v, occupancy: occupancy, logQueue: logQueue}
+			grid.vertices[y][x] = p
+		}
+	}
+	return grid
+}
+
+// startTravellers runs every explorer in its own routine, the explorers take
+// the vertices they move to. One more routine spawns the new explorers.
+func startTravellers(vertices [][]Vertex, params *runParams, wg *sync.WaitGroup) {
+	grid := newPassiveGrid(vertices, params.logQueue)
+	for y := range grid.vertices {
+		for x, p := range grid.vertices[y] {
+			// explorers placed before the start get their own routines
+			for _, e := range vertices[y][x].explorers {
+				p.acquire()
+				wg.Add(1)
+				go traveller(grid, e.id, p, params, wg)
+			}
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		spawner(grid, params, wg)
+		wg.Done()
+	}()
+}
+
+// spawner gives every vertex the chance to spawn an explorer once per spawn
+// tick, like the spawn case of runner
+func spawner(grid *passiveGrid, params *runParams, wg *sync.WaitGroup) {
+	population := params.population
+	boundary := params.boundary
+
+	for !params.quit.Load() {
+		time.Sleep(params.spawnTick)
+		for y := range grid.vertices {
+			for _, p := range grid.vertices[y] {
+				var chance float64
+				var sides []LogDirection
+				if boundary == nil {
+					chance = population.spawnChance()
+				} else if sides = p.vertex.openSides(); len(sides) > 0 {
+					chance = boundary.arrivalRate
+				}
+				if rand.Float64() >= chance || !p.acquire() {
+					continue
+				}
+				id, ok := population.admit()
+				if !ok {
+					p.release()
+					continue
+				}
+				if boundary == nil {
+					p.logger.LogExplorerSpawned(id)
+				} else {
+					p.logger.LogExplorerEntered(id, sides[rand.Intn(len(sides))])
+				}
+				wg.Add(1)
+				go traveller(grid, id, p, params, wg)
+			}
+		}
+	}
+}
+
+// traveller is the routine of one explorer standing on the vertex at, it
+// does what the explore case of runner does for the explorers of a vertex
+func traveller(grid *passiveGrid, id int, at *passiveVertex, params *runParams, wg *sync.WaitGroup) {
+	defer wg.Done()
+	population := params.population
+	boundary := params.boundary
+
+	for !params.quit.Load() {
+		time.Sleep(params.moveTick)
+
+		if rand.Float64() < at.perExplorer(population.despawnChance()) {
+			at.release()
+			population.leave()
+			at.logger.LogExplorerDespawned(id)
+			return
+		}
+		if rand.Float64() >= moveExplorerRate {
+			continue
+		}
+		if sides := at.vertex.openSides(); boundary != nil && len(sides) > 0 && rand.Float64() < at.perExplorer(boundary.exitRate) {
+			at.release()
+			population.leave()
+			at.logger.LogExplorerExited(id, sides[rand.Intn(len(sides))])
+			return
+		}
+
+		// ask the neighbours in random order, the first free one is taken
+		for _, i := range rand.Perm(4) {
+			direction := []LogDirection{North, South, East, West}[i]
+			x, y := step(at.vertex.x, at.vertex.y, direction)
+			if x < 0 || x >= grid.n || y < 0 || y >= grid.m || at.vertex.blocked[direction] {
+				continue
+			}
+			next := grid.vertices[y][x]
+			if !next.acquire() {
+				continue
+			}
+			at.release()
+			at.logger.LogExplorerSend(id, direction)
+			next.logger.LogExplorerReceived(id)
+			at = next
+			break
+		}
+	}
+}
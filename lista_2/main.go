@@ -1,45 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"lista_1/sim"
 	"os"
 	"strconv"
-	"sync"
-	"sync/atomic"
-	"time"
 )
 
-// TODO: Make error messages more meaningful
-// TODO: Split constants to correct files
-const (
-	tickTime             = 50 * time.Millisecond
-	spawnExplorerRate    = 0.05
-	moveExplorerRate     = 0.10
-	spawnHazardRate      = 0.05
-	hazardLifeTime       = 10 * tickTime
-	spawnWildLocatorRate = 0.05
-	WildLocatorLifeTime  = 10 * tickTime
-	logBuffer            = 100
-	runTime              = 5 * time.Second
-	cameraTick           = 100 * time.Millisecond
-	cameraBuffer         = 100
-)
-
-type ExplorerStats struct {
-	count  int
-	nextId int
-	mu     sync.Mutex
-}
-
-var shouldQuit atomic.Bool = atomic.Bool{}
-
 func main() {
-	explorerStats := ExplorerStats{count: 0, nextId: 1}
+	seed := flag.Uint64("seed", 0, "seed of the random number generator, 0 picks one from the clock")
+	checkpointPath := flag.String("checkpoint", "", "write a checkpoint to this file at the end of the run and on SIGUSR1")
+	resumePath := flag.String("resume", "", "start the run from a checkpoint file")
+	consoleAt := flag.String("console", "", "accept commands from stdin (stdin) or from a unix socket at this path")
+	configPath := flag.String("config", "", "json file with the explorer teams, energy, charging stations and vertex capacities")
+	workers := flag.Int("workers", 1, "split the rows of the lattice between this many worker processes")
+	network := flag.String("network", "unix", "how the processes of a distributed run talk to each other: unix or tcp")
+	workerIndex := flag.Int("worker", -1, "run one region of a distributed run, set by the coordinator")
+	workerDir := flag.String("worker-dir", "", "directory where the processes of a distributed run find each other, set by the coordinator")
+	observeAt := flag.String("observe", "", "stream the camera events to observers connecting to this tcp address")
+	recordPath := flag.String("record", "", "record the camera to an asciinema cast file")
+	logSinks := flag.String("log", "log.txt", "comma separated places to write the log to: file paths, stdout, stderr or none")
+	logMaxSize := flag.Int64("log-max-size", 0, "rotate a log file when it grows over this many bytes, 0 never rotates")
+	logKeep := flag.Int("log-keep", 3, "how many rotated log files are kept")
+	logEvents := flag.String("log-events", "all", "comma separated event types written to the log, for example explorerMoved,explorerDied")
+	logBufferSize := flag.Int("log-buffer", sim.DefaultLogBuffer, "how many events wait for the logger before the overflow policy kicks in")
+	logOverflow := flag.String("log-overflow", "block", "what a full log buffer does: block the simulation, drop-oldest or drop-newest events")
+	logOrder := flag.String("log-order", "arrival", "order the log is written in: arrival or causal, which holds events back until what happened before them is written")
+	logHoldbackTime := flag.Duration("log-holdback", sim.DefaultLogHoldback, "how long the causal log order waits for events that happened before")
+	poolWorkers := flag.Int("pool", 0, "run the vertices on this many pool workers, each owning a block of the lattice, instead of a routine per vertex, explorer and wild locator")
+	clocks := flag.String("clocks", "lamport", "logical clocks that stamp the log events: lamport, vector or none")
+	runTime := flag.Duration("run-time", 0, "how long the simulation runs, 0 runs for the default time")
+	flag.Parse()
+
+	if flag.NArg() > 0 && flag.Arg(0) == "watch" {
+		if flag.NArg() != 2 {
+			panic("Usage: watch HOST:PORT")
+		}
+		err := sim.Watch(flag.Arg(1), *recordPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not watch the simulation:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config, err := sim.ReadConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	logConfig, err := sim.ParseLogConfig(*logSinks, *logEvents, *logMaxSize, *logKeep, *logBufferSize, *logOverflow, *logOrder, *logHoldbackTime)
+	if err != nil {
+		panic(err)
+	}
+	clockMode, err := sim.ParseClockMode(*clocks)
+	if err != nil {
+		panic(err)
+	}
+	if logConfig.Order == sim.OrderCausal && clockMode == sim.ClocksNone {
+		panic("The causal log order needs -clocks lamport or vector")
+	}
 
 	n := 10
 	m := 10
-	err := error(nil)
-	args := os.Args[1:]
+	args := flag.Args()
 	if len(args) == 1 {
 		n, err = strconv.Atoi(args[0])
 		if err != nil {
@@ -59,60 +84,94 @@ func main() {
 		panic("Too many arguments")
 	}
 
-	maxExplorers := n * m
-
-	lattice := CreateLattice(n, m)
-	logChannel := make(chan LogMessage, logBuffer)
-	loggerDone := make(chan bool)
-	cameraChanel := make(chan CameraMessage, cameraBuffer)
-	cameraDone := make(chan bool)
-
-	go func() {
-		loggerRun(logChannel, cameraChanel)
-		loggerDone <- true
-	}()
-
-	go func() {
-		camera := NewCamera(cameraChanel, n, m)
-		camera.Start()
-		cameraDone <- true
-	}()
-
-	vertexWg := sync.WaitGroup{}
-	vertexWg.Add(n * m)
-
-	explorerWg := sync.WaitGroup{}
-	wildLocatorWg := sync.WaitGroup{}
-
-	for i := 0; i < n; i++ {
-		for j := 0; j < m; j++ {
-			go func(v Vertex) {
-				v.run(&explorerWg, &explorerStats, &wildLocatorWg, maxExplorers, logChannel, &lattice)
-				vertexWg.Done()
-			}(lattice.vertices[i][j])
+	var resume *sim.Snapshot
+	if *resumePath != "" {
+		snapshot, err := sim.ReadCheckpoint(*resumePath)
+		if err != nil {
+			panic(err)
+		}
+		if len(args) > 0 && (n != snapshot.N || m != snapshot.M) {
+			fmt.Fprintf(os.Stderr, "WARNING: using the %dx%d lattice of the checkpoint instead of %dx%d\n", snapshot.N, snapshot.M, n, m)
 		}
+		n, m = snapshot.N, snapshot.M
+		resume = &snapshot
 	}
 
-	time.Sleep(runTime)
-
-	fmt.Println("INFO: starting the exit sequence")
-
-	shouldQuit.Store(true)
-
-	vertexWg.Wait()
-	fmt.Println("INFO: all vertex routines finished")
-
-	explorerWg.Wait()
-	fmt.Println("INFO: all explorer routines finished")
+	if n < 1 || m < 1 {
+		panic("Lattice dimensions must be positive")
+	}
 
-	wildLocatorWg.Wait()
-	fmt.Println("INFO: all wild locator routines finished")
+	opts := sim.Options{
+		N:              n,
+		M:              m,
+		Seed:           *seed,
+		Config:         config,
+		ConfigPath:     *configPath,
+		Log:            logConfig,
+		RunTime:        *runTime,
+		Resume:         resume,
+		CheckpointPath: *checkpointPath,
+		ConsoleAt:      *consoleAt,
+		ObserveAt:      *observeAt,
+		Clocks:         clockMode,
+		Pool:           *poolWorkers,
+		Workers:        *workers,
+		Network:        *network,
+		WorkerIndex:    *workerIndex,
+		WorkerDir:      *workerDir,
+	}
 
-	close(logChannel)
+	if *workers > 1 {
+		if *workers > m {
+			panic("There can't be more workers than rows of the lattice")
+		}
+		if *network != "unix" && *network != "tcp" {
+			panic("The network has to be unix or tcp")
+		}
+		if *resumePath != "" || *checkpointPath != "" || *consoleAt != "" {
+			panic("Checkpoints and the console don't work in a distributed run")
+		}
+		if *poolWorkers > 0 {
+			panic("The pool doesn't work in a distributed run")
+		}
+		if *workerIndex >= 0 {
+			err = sim.RunWorker(opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: worker %d failed: %v\n", *workerIndex, err)
+				os.Exit(1)
+			}
+			return
+		}
+		output, closeOutput, err := sim.OpenOutput(*recordPath, n, m)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not open the recording:", err)
+			os.Exit(1)
+		}
+		defer closeOutput()
+		opts.Output = output
+		err = sim.RunCoordinator(opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not run the workers:", err)
+			closeOutput()
+			os.Exit(1)
+		}
+		return
+	}
 
-	<-loggerDone
-	fmt.Println("INFO: logger routine finished")
+	output, closeOutput, err := sim.OpenOutput(*recordPath, n, m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: could not open the recording:", err)
+		os.Exit(1)
+	}
+	defer closeOutput()
+	opts.Output = output
 
-	<-cameraDone
-	fmt.Println("INFO: camera routine finished")
+	simulation, err := sim.New(opts)
+	if err != nil {
+		panic(err)
+	}
+	err = simulation.Run(context.Background())
+	if err != nil {
+		panic(err)
+	}
 }
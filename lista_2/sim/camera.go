@@ -0,0 +1,430 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	TERM_RESET    = "\033[0m"
+	TERM_RED      = "\033[31m"
+	TERM_GREEN    = "\033[32m"
+	TERM_YELLOW   = "\033[33m"
+	TERM_BLUE     = "\033[34m"
+	TERM_MAGENTA  = "\033[35m"
+	TERM_CYAN     = "\033[36m"
+	TERM_BG_GREEN = "\033[42m"
+)
+
+type Camera struct {
+	cameraChannel <-chan CameraMessage
+	board         [][]cell
+	n             int
+	m             int
+	crossedEdges  *crossedEdges
+	oneWay        *oneWayEdges
+	out           io.Writer
+}
+
+type CameraMessage struct {
+	messageType CameraMessageType
+	x           int
+	y           int
+	expId       int
+	colour      string
+	xHelper     int
+	yHelper     int
+}
+
+// cell is what the camera knows about one vertex
+type cell struct {
+	explorers      []occupant
+	hazard         bool
+	hasWildLocator bool
+	charging       bool
+}
+
+func (c cell) String() string {
+	if c.charging {
+		// the background marks a charging station whatever stands on it
+		content := c.content()
+		if c.colour() == "" {
+			content += TERM_RESET
+		}
+		return TERM_BG_GREEN + content
+	}
+	return c.content()
+}
+
+type occupant struct {
+	expId  int
+	colour string
+}
+
+// colour is the colour shared by every explorer in the cell, explorers of
+// different teams in one cell are drawn without a colour
+func (c cell) colour() string {
+	if len(c.explorers) == 0 {
+		return ""
+	}
+	colour := c.explorers[0].colour
+	for _, e := range c.explorers[1:] {
+		if e.colour != colour {
+			return ""
+		}
+	}
+	return colour
+}
+
+// occupancy shows the id of a lone explorer and the number of explorers when
+// there are more of them, a cell is only two characters wide
+func (c cell) occupancy() string {
+	switch {
+	case len(c.explorers) == 1:
+		return fmt.Sprintf("%02d", c.explorers[0].expId)
+	case len(c.explorers) < 10:
+		return fmt.Sprintf("x%d", len(c.explorers))
+	default:
+		return "++"
+	}
+}
+
+func (c cell) content() string {
+	switch {
+	case len(c.explorers) > 0 && c.colour() != "":
+		return c.colour() + c.occupancy() + TERM_RESET
+	case len(c.explorers) > 0:
+		return c.occupancy()
+	case c.hazard && c.hasWildLocator:
+		return "#*"
+	case c.hazard:
+		return "# "
+	case c.hasWildLocator:
+		return " *"
+	default:
+		return "  "
+	}
+}
+
+type CameraMessageType int
+
+const (
+	CamExplorerSpawned CameraMessageType = iota
+	CamExplorerMoved
+	CamHazardSpawned
+	CamHazardRemoved
+	CamExplorerRemoved
+	CamWildLocatorSpawned
+	CamWildLocatorMoved
+	CamWildLocatorRemoved
+	CamChargingStation
+	CamOneWayEdge
+)
+
+func RecordSpawnExplorer(expId int, colour string, x, y int) CameraMessage {
+	return CameraMessage{expId: expId, colour: colour, x: x, y: y, messageType: CamExplorerSpawned}
+}
+
+func RecordMoveExplorer(expId int, colour string, fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{expId: expId, colour: colour, x: fromX, y: fromY, xHelper: toX, yHelper: toY, messageType: CamExplorerMoved}
+}
+
+func RecordSpawnHazard(x, y int) CameraMessage {
+	return CameraMessage{messageType: CamHazardSpawned, x: x, y: y}
+}
+
+func RecordRemoveHazard(x, y int) CameraMessage {
+	return CameraMessage{messageType: CamHazardRemoved, x: x, y: y}
+}
+
+func RecordRemoveExplorer(expId, x, y int) CameraMessage {
+	return CameraMessage{messageType: CamExplorerRemoved, x: x, y: y, expId: expId}
+}
+
+func RecordSpawnWildLocator(x, y int) CameraMessage {
+	return CameraMessage{messageType: CamWildLocatorSpawned, x: x, y: y}
+}
+
+func RecordMoveWildLocator(fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{messageType: CamWildLocatorMoved, x: fromX, y: fromY, xHelper: toX, yHelper: toY}
+}
+
+func RecordRemoveWildLocator(x, y int) CameraMessage {
+	return CameraMessage{messageType: CamWildLocatorRemoved, x: x, y: y}
+}
+
+func RecordChargingStation(x, y int) CameraMessage {
+	return CameraMessage{messageType: CamChargingStation, x: x, y: y}
+}
+
+// RecordOneWayEdge records that the edge can only be crossed from (fromX,
+// fromY) to (toX, toY)
+func RecordOneWayEdge(fromX, fromY, toX, toY int) CameraMessage {
+	return CameraMessage{messageType: CamOneWayEdge, x: fromX, y: fromY, xHelper: toX, yHelper: toY}
+}
+
+func (c *cell) addExplorer(expId int, colour string) {
+	c.explorers = append(c.explorers, occupant{expId, colour})
+}
+
+func (c *cell) removeExplorer(expId int) {
+	for i, e := range c.explorers {
+		if e.expId == expId {
+			c.explorers = append(c.explorers[:i], c.explorers[i+1:]...)
+			return
+		}
+	}
+}
+
+// frameSize is the width and height of the frames PrintBoard draws: 3
+// characters per vertex wide, 2 lines per vertex tall and one more for each
+// border
+func frameSize(n, m int) (int, int) {
+	return 3*n + 1, 2*m + 1
+}
+
+func (c Camera) PrintBoard() {
+	// the frame is written at once, so a recording gets it as one event
+	frame := &strings.Builder{}
+	c.PrintBoardSeparator(frame)
+	bottomRow := "+"
+	for y := 0; y < c.m; y++ {
+		fmt.Fprint(frame, "|")
+		for x := 0; x < c.n; x++ {
+			vertId := y*c.n + x
+
+			fmt.Fprint(frame, c.board[y][x])
+
+			if x < c.n-1 {
+				arrow := arrows[c.oneWay.east[vertId]]
+				if colour := c.crossedEdges.east[vertId]; colour != "" {
+					if arrow == "" {
+						arrow = "|"
+					}
+					fmt.Fprintf(frame, "%s%s%s", colour, arrow, TERM_RESET)
+				} else if arrow != "" {
+					fmt.Fprint(frame, arrow)
+				} else {
+					fmt.Fprintf(frame, " ")
+				}
+			} else {
+				fmt.Fprintln(frame, "|")
+			}
+
+			if y < c.m-1 {
+				arrow := arrows[c.oneWay.south[vertId]]
+				if colour := c.crossedEdges.south[vertId]; colour != "" {
+					if arrow == "" {
+						arrow = "--"
+					}
+					bottomRow += fmt.Sprintf("%s%s%s+", colour, arrow, TERM_RESET)
+				} else if arrow != "" {
+					bottomRow += arrow + "+"
+				} else {
+					bottomRow += "  +"
+				}
+			}
+		}
+		if y < c.m-1 {
+			fmt.Fprintln(frame, bottomRow)
+			bottomRow = "+"
+		}
+	}
+	c.PrintBoardSeparator(frame)
+	c.ClearEdges()
+	io.WriteString(c.out, frame.String())
+}
+
+func (c Camera) Start() {
+	// TODO: add checks if operations that we perform make sens
+	ticker := time.NewTicker(cameraTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.PrintBoard()
+		case msg, ok := <-c.cameraChannel:
+			if !ok {
+				return
+			}
+			c.apply(msg)
+		}
+	}
+}
+
+// apply updates the board with one event
+func (c Camera) apply(msg CameraMessage) {
+	switch msg.messageType {
+	case CamExplorerSpawned:
+		c.board[msg.y][msg.x].addExplorer(msg.expId, msg.colour)
+	case CamExplorerMoved:
+		c.board[msg.y][msg.x].removeExplorer(msg.expId)
+		c.board[msg.yHelper][msg.xHelper].addExplorer(msg.expId, msg.colour)
+		c.crossedEdges.Mark(msg.x, msg.y, msg.xHelper, msg.yHelper, TERM_RED)
+	case CamHazardSpawned:
+		c.board[msg.y][msg.x].hazard = true
+	case CamHazardRemoved:
+		c.board[msg.y][msg.x].hazard = false
+	case CamExplorerRemoved:
+		c.board[msg.y][msg.x].removeExplorer(msg.expId)
+	case CamWildLocatorSpawned:
+		c.board[msg.y][msg.x].hasWildLocator = true
+	case CamWildLocatorMoved:
+		c.board[msg.y][msg.x].hasWildLocator = false
+		c.board[msg.yHelper][msg.xHelper].hasWildLocator = true
+		c.crossedEdges.Mark(msg.x, msg.y, msg.xHelper, msg.yHelper, TERM_YELLOW)
+	case CamWildLocatorRemoved:
+		c.board[msg.y][msg.x].hasWildLocator = false
+	case CamChargingStation:
+		c.board[msg.y][msg.x].charging = true
+	case CamOneWayEdge:
+		c.oneWay.Set(msg.x, msg.y, msg.xHelper, msg.yHelper)
+	}
+}
+
+func (c Camera) ClearEdges() {
+	c.crossedEdges.Clear()
+}
+
+func (c Camera) PrintBoardSeparator(w io.Writer) {
+	fmt.Fprint(w, "+")
+	for i := 0; i < c.n; i++ {
+		fmt.Fprint(w, "--+")
+	}
+	fmt.Fprintln(w)
+}
+
+func NewCamera(cameraChannel <-chan CameraMessage, n, m int, out io.Writer) Camera {
+	board := make([][]cell, m)
+	for y := 0; y < m; y++ {
+		board[y] = make([]cell, n)
+	}
+
+	crossedEdges := newCrossedEdges(n, m)
+
+	return Camera{cameraChannel: cameraChannel, board: board, n: n, m: m, crossedEdges: crossedEdges, oneWay: newOneWayEdges(n, m), out: out}
+}
+
+// crossedEdges stores the colour of every real lattice edge crossed since the
+// last Clear instead of a full adjacency matrix: east[id] is the edge between
+// vertex id and its east neighbour and south[id] the edge between vertex id
+// and its south neighbour, an empty colour means the edge was not crossed.
+// The vertices crossed since the last Clear are remembered, so clearing costs
+// only as much as the number of edges that were actually crossed.
+type crossedEdges struct {
+	n       int
+	east    []string
+	south   []string
+	touched []int
+}
+
+func newCrossedEdges(n, m int) *crossedEdges {
+	return &crossedEdges{n: n, east: make([]string, n*m), south: make([]string, n*m)}
+}
+
+// Mark remembers that the edge was crossed, explorers are drawn over wild
+// locators when both crossed the same edge
+func (e *crossedEdges) Mark(fromX, fromY, toX, toY int, colour string) {
+	id, east, ok := edgeOf(e.n, fromX, fromY, toX, toY)
+	if !ok {
+		return
+	}
+	edge := &e.south[id]
+	if east {
+		edge = &e.east[id]
+	}
+	if *edge != TERM_RED {
+		*edge = colour
+	}
+	e.touched = append(e.touched, id)
+}
+
+func (e *crossedEdges) Clear() {
+	for _, id := range e.touched {
+		e.east[id] = ""
+		e.south[id] = ""
+	}
+	e.touched = e.touched[:0]
+}
+
+// edgeOf returns the vertex the edge between two neighbours is stored at and
+// whether it is the east or the south edge of that vertex
+func edgeOf(n, fromX, fromY, toX, toY int) (id int, east bool, ok bool) {
+	// an edge is always stored at its north-west end
+	x, y := fromX, fromY
+	if toX < x || toY < y {
+		x, y = toX, toY
+	}
+	id = y*n + x
+
+	switch {
+	case fromY == toY && (fromX-toX == 1 || toX-fromX == 1):
+		return id, true, true
+	case fromX == toX && (fromY-toY == 1 || toY-fromY == 1):
+		return id, false, true
+	default:
+		return 0, false, false
+	}
+}
+
+// arrows are drawn on the one way edges, the east edges are one character
+// wide and the south edges two
+var arrows = map[LogDirection]string{
+	East:  ">",
+	West:  "<",
+	South: "vv",
+	North: "^^",
+}
+
+// oneWayEdges stores the direction of every one way edge the same way
+// crossedEdges stores the crossed ones, None is an edge that goes both ways
+type oneWayEdges struct {
+	n     int
+	east  []LogDirection
+	south []LogDirection
+}
+
+func newOneWayEdges(n, m int) *oneWayEdges {
+	return &oneWayEdges{n: n, east: make([]LogDirection, n*m), south: make([]LogDirection, n*m)}
+}
+
+func (e *oneWayEdges) Set(fromX, fromY, toX, toY int) {
+	id, east, ok := edgeOf(e.n, fromX, fromY, toX, toY)
+	if !ok {
+		return
+	}
+	switch {
+	case east && toX > fromX:
+		e.east[id] = East
+	case east:
+		e.east[id] = West
+	case toY > fromY:
+		e.south[id] = South
+	default:
+		e.south[id] = North
+	}
+}
+
+// messages returns the events that set every one way edge again
+func (e *oneWayEdges) messages() []CameraMessage {
+	var messages []CameraMessage
+	for id := range e.east {
+		x, y := id%e.n, id/e.n
+		switch e.east[id] {
+		case East:
+			messages = append(messages, RecordOneWayEdge(x, y, x+1, y))
+		case West:
+			messages = append(messages, RecordOneWayEdge(x+1, y, x, y))
+		}
+		switch e.south[id] {
+		case South:
+			messages = append(messages, RecordOneWayEdge(x, y, x, y+1))
+		case North:
+			messages = append(messages, RecordOneWayEdge(x, y+1, x, y))
+		}
+	}
+	return messages
+}
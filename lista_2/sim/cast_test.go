@@ -0,0 +1,68 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCastRecordsBoard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "board.cast")
+	width, height := frameSize(2, 2)
+	recorder, err := NewCastRecorder(path, io.Discard, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	camera := NewCamera(nil, 2, 2, recorder)
+	camera.board[0][1].hazard = true
+	camera.board[1][0].hasWildLocator = true
+	camera.PrintBoard()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, event := readCast(t, path)
+	if header.Version != 2 || header.Width != 7 || header.Height != 5 {
+		t.Errorf("header is %+v, want version 2 and a 7x5 terminal", header)
+	}
+	want := "+--+--+\r\n" +
+		"|   # |\r\n" +
+		"+  +  +\r\n" +
+		"| *   |\r\n" +
+		"+--+--+\r\n"
+	if event[1] != "o" || event[2] != want {
+		t.Errorf("first event is %q, want an output of\n%s", event, want)
+	}
+	if lines := strings.Count(event[2].(string), "\r\n"); lines != header.Height {
+		t.Errorf("frame has %d lines, the header says %d", lines, header.Height)
+	}
+}
+
+// readCast reads the header and the first event of a recording
+func readCast(t *testing.T, path string) (castHeader, []interface{}) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var header castHeader
+	var event []interface{}
+	scanner := bufio.NewScanner(file)
+	for _, v := range []interface{}{&header, &event} {
+		if !scanner.Scan() {
+			t.Fatalf("recording ends early: %v", scanner.Err())
+		}
+		if err := json.Unmarshal(scanner.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(event) != 3 {
+		t.Fatalf("event %v is not [time, type, data]", event)
+	}
+	return header, event
+}
@@ -0,0 +1,149 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+// LogOrder says in which order the logger writes the events
+type LogOrder int
+
+const (
+	// OrderArrival writes the events as they reach the logger
+	OrderArrival LogOrder = iota
+	// OrderCausal holds events back until the events that happened before
+	// them are written
+	OrderCausal
+)
+
+var logOrderNames = map[string]LogOrder{
+	"arrival": OrderArrival,
+	"causal":  OrderCausal,
+}
+
+func (o LogOrder) String() string {
+	for name, order := range logOrderNames {
+		if order == o {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func parseLogOrder(name string) (LogOrder, error) {
+	order, ok := logOrderNames[name]
+	if !ok {
+		return OrderArrival, fmt.Errorf("unknown log order %q, use arrival or causal", name)
+	}
+	return order, nil
+}
+
+// heldLog is an event the causal order is holding back
+type heldLog struct {
+	log     LogMessage
+	arrived time.Time
+}
+
+// causalOrder passes the events on so that no event comes before one that
+// happened before it. With vector clocks an event waits exactly until the
+// events it depends on are passed on. Lamport times alone can't tell that, so
+// every event waits for the holdback and the waiting ones go out by their
+// Lamport time. An event that waited longer than the holdback goes out
+// anyway, what it waits for may have been dropped by the log queue.
+// Unstamped events, like the markers of lost events, are not held. The
+// holdback is measured on the clock of the run.
+func causalOrder(in <-chan LogMessage, holdback time.Duration, clock Clock) <-chan LogMessage {
+	out := make(chan LogMessage)
+
+	go func() {
+		defer close(out)
+		// a tiny holdback still can't make the ticker tick in no time
+		period := holdback / 4
+		if period < minHoldbackTick {
+			period = minHoldbackTick
+		}
+		ticker := clock.NewTicker(period)
+		defer ticker.Stop()
+
+		// held is sorted by Lamport time, ties keep the order of arrival
+		var held []heldLog
+		delivered := make(map[string]uint64)
+
+		release := func(now time.Time, all bool) {
+			for {
+				i := nextCausal(held, delivered, now, holdback, all)
+				if i < 0 {
+					return
+				}
+				log := held[i].log
+				held = append(held[:i], held[i+1:]...)
+				process := log.stamp.Process
+				if t := log.stamp.Vector[process]; t > delivered[process] {
+					delivered[process] = t
+				}
+				out <- log
+			}
+		}
+
+		for {
+			select {
+			case log, ok := <-in:
+				now := clock.Now()
+				if !ok {
+					release(now, true)
+					return
+				}
+				if log.stamp.Lamport == 0 {
+					out <- log
+					continue
+				}
+				i := len(held)
+				for i > 0 && held[i-1].log.stamp.Lamport > log.stamp.Lamport {
+					i--
+				}
+				held = append(held, heldLog{})
+				copy(held[i+1:], held[i:])
+				held[i] = heldLog{log: log, arrived: now}
+				release(now, false)
+			case now := <-ticker.C():
+				release(now, false)
+			}
+		}
+	}()
+
+	return out
+}
+
+// nextCausal returns the index of the held event that can go out next or -1
+func nextCausal(held []heldLog, delivered map[string]uint64, now time.Time, holdback time.Duration, all bool) int {
+	for i, h := range held {
+		waited := all || now.Sub(h.arrived) >= holdback
+		if h.log.stamp.Vector == nil {
+			// only Lamport times, the earliest goes first once it waited
+			if waited {
+				return i
+			}
+			return -1
+		}
+		if waited || causallyReady(h.log.stamp, delivered) {
+			return i
+		}
+	}
+	return -1
+}
+
+// causallyReady tells whether every event that happened before the stamped
+// one was passed on: the previous event of its own process and everything
+// it heard of from the other processes
+func causallyReady(s Stamp, delivered map[string]uint64) bool {
+	for process, t := range s.Vector {
+		if process == s.Process {
+			if delivered[process]+1 < t {
+				return false
+			}
+		} else if delivered[process] < t {
+			return false
+		}
+	}
+	return true
+}
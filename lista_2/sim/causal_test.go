@@ -0,0 +1,84 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCausalOrderTinyHoldback(t *testing.T) {
+	in := make(chan LogMessage)
+	out := causalOrder(in, time.Nanosecond, realClock{})
+
+	go func() {
+		for i := uint64(1); i <= 3; i++ {
+			in <- LogMessage{stamp: Stamp{Process: "v0", Lamport: i, Vector: map[string]uint64{"v0": i}}}
+		}
+		close(in)
+	}()
+	var lamport uint64
+	for log := range out {
+		if log.stamp.Lamport != lamport+1 {
+			t.Fatalf("got Lamport time %d after %d", log.stamp.Lamport, lamport)
+		}
+		lamport = log.stamp.Lamport
+	}
+	if lamport != 3 {
+		t.Errorf("got %d events, want 3", lamport)
+	}
+}
+
+func TestCausalOrderTwoProcesses(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector bool
+	}{
+		// vector clocks let the received go as soon as the moved did
+		{"vector", true},
+		// Lamport times alone wait for the holdback
+		{"holdback", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			moved := LogMessage{logType: LogMsgExplorerMoved, stamp: Stamp{Process: "e1", Lamport: 1}}
+			received := LogMessage{logType: LogMsgExplorerReceived, stamp: Stamp{Process: "v1", Lamport: 2}}
+			if test.vector {
+				moved.stamp.Vector = map[string]uint64{"e1": 1}
+				received.stamp.Vector = map[string]uint64{"e1": 1, "v1": 1}
+			}
+
+			clock := newFakeClock()
+			start := clock.Now()
+			holdback := time.Second
+			in := make(chan LogMessage)
+			out := causalOrder(in, holdback, clock)
+			// the vertex of the other process got its log in first
+			in <- received
+			in <- moved
+
+			var got []LogType
+			deadline := time.After(wait)
+			for len(got) < 2 {
+				select {
+				case log := <-out:
+					if !test.vector && clock.Now().Sub(start) < holdback {
+						t.Fatalf("%v went out before the holdback", log)
+					}
+					got = append(got, log.logType)
+				case <-time.After(10 * time.Millisecond):
+					if !test.vector {
+						clock.Advance(holdback)
+					}
+				case <-deadline:
+					t.Fatalf("only got %v", got)
+				}
+			}
+			close(in)
+			if got[0] != LogMsgExplorerMoved || got[1] != LogMsgExplorerReceived {
+				t.Errorf("got %v, want the moved before the received", got)
+			}
+			if _, ok := <-out; ok {
+				t.Error("an event came out twice")
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package sim
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Snapshot is the complete state of a run, written to a checkpoint file and
+// used to start a new run from the same world
+type Snapshot struct {
+	N              int                `json:"n"`
+	M              int                `json:"m"`
+	TakenAt        time.Time          `json:"takenAt"`
+	Explorers      []ExplorerState    `json:"explorers"`
+	Hazards        []HazardState      `json:"hazards"`
+	WildLocators   []WildLocatorState `json:"wildLocators"`
+	ExplorerCount  int                `json:"explorerCount"`
+	NextExplorerId int                `json:"nextExplorerId"`
+	LastWildId     int                `json:"lastWildLocatorId"`
+	Rng            []byte             `json:"rng"`
+	// ChargingStations and the capacities are the layout the run had, the
+	// random stations are drawn only once. Checkpoints without them keep
+	// the layout from the config.
+	ChargingStations []PositionConfig `json:"chargingStations"`
+	Capacity         int              `json:"capacity,omitempty"`
+	Capacities       []CapacityConfig `json:"capacities,omitempty"`
+}
+
+type ExplorerState struct {
+	Id     int     `json:"id"`
+	Team   string  `json:"team"`
+	Energy float64 `json:"energy"`
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+}
+
+type HazardState struct {
+	X        int           `json:"x"`
+	Y        int           `json:"y"`
+	LifeLeft time.Duration `json:"lifeLeft"`
+}
+
+type WildLocatorState struct {
+	Id       int           `json:"id"`
+	X        int           `json:"x"`
+	Y        int           `json:"y"`
+	LifeLeft time.Duration `json:"lifeLeft"`
+}
+
+type position struct {
+	x int
+	y int
+}
+
+type trackedWildLocator struct {
+	id        int
+	expiresAt time.Time
+}
+
+type trackedExplorer struct {
+	position
+	team   string
+	energy float64
+}
+
+// worldTracker rebuilds the state of the world from the stream of log
+// messages, the same way the camera rebuilds the board. The logger feeds it
+// and main reads it whenever a checkpoint is requested.
+type worldTracker struct {
+	mu           sync.Mutex
+	n            int
+	m            int
+	explorers    map[int]trackedExplorer
+	hazards      map[position]time.Time
+	wildLocators map[position]trackedWildLocator
+}
+
+func newWorldTracker(n, m int) *worldTracker {
+	return &worldTracker{
+		n:            n,
+		m:            m,
+		explorers:    make(map[int]trackedExplorer),
+		hazards:      make(map[position]time.Time),
+		wildLocators: make(map[position]trackedWildLocator),
+	}
+}
+
+func (t *worldTracker) Record(log LogMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch log.logType {
+	case LogMsgExplorerSpawned:
+		t.explorers[log.expId] = trackedExplorer{position{log.fromX, log.fromY}, log.team.name, log.energy}
+	case LogMsgExplorerMoved:
+		t.explorers[log.expId] = trackedExplorer{position{log.toX, log.toY}, log.team.name, log.energy}
+	case LogMsgExplorerDied, LogMsgExplorerExhausted:
+		delete(t.explorers, log.expId)
+	case LogMsgHazardSpawned:
+		t.hazards[position{log.toX, log.toY}] = log.timestamp.Add(log.lifeTime)
+	case LogMsgHazardDisappeared, LogMsgExplorerEnteredHazard:
+		delete(t.hazards, position{log.toX, log.toY})
+	case LogMsgWildLocatorSpawned:
+		t.wildLocators[position{log.fromX, log.fromY}] = trackedWildLocator{log.wildId, log.timestamp.Add(log.lifeTime)}
+	case LogMsgWildLocatorMoved:
+		from := position{log.fromX, log.fromY}
+		t.wildLocators[position{log.toX, log.toY}] = t.wildLocators[from]
+		delete(t.wildLocators, from)
+	case LogMsgWildLocatorDied:
+		delete(t.wildLocators, position{log.fromX, log.fromY})
+	}
+}
+
+// Snapshot returns the tracked world with lifetimes measured from at
+func (t *worldTracker) Snapshot(at time.Time) Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := Snapshot{
+		N:            t.n,
+		M:            t.m,
+		TakenAt:      at,
+		Explorers:    make([]ExplorerState, 0, len(t.explorers)),
+		Hazards:      make([]HazardState, 0, len(t.hazards)),
+		WildLocators: make([]WildLocatorState, 0, len(t.wildLocators)),
+	}
+	for id, e := range t.explorers {
+		snapshot.Explorers = append(snapshot.Explorers, ExplorerState{Id: id, Team: e.team, Energy: e.energy, X: e.x, Y: e.y})
+	}
+	for pos, expiresAt := range t.hazards {
+		snapshot.Hazards = append(snapshot.Hazards, HazardState{X: pos.x, Y: pos.y, LifeLeft: lifeLeft(expiresAt, at)})
+	}
+	for pos, w := range t.wildLocators {
+		snapshot.WildLocators = append(snapshot.WildLocators, WildLocatorState{Id: w.id, X: pos.x, Y: pos.y, LifeLeft: lifeLeft(w.expiresAt, at)})
+	}
+	return snapshot
+}
+
+func lifeLeft(expiresAt, at time.Time) time.Duration {
+	left := expiresAt.Sub(at)
+	if left <= 0 {
+		// it is about to disappear, give it the shortest possible life
+		left = time.Nanosecond
+	}
+	return left
+}
+
+func takeSnapshot(tracker *worldTracker, lattice *Lattice, explorerStats *ExplorerStats, env *Env, at time.Time) Snapshot {
+	snapshot := tracker.Snapshot(at)
+	snapshot.ExplorerCount = len(snapshot.Explorers)
+	lattice.saveLayout(&snapshot)
+
+	explorerStats.mu.Lock()
+	snapshot.NextExplorerId = explorerStats.nextId
+	explorerStats.mu.Unlock()
+	snapshot.LastWildId = int(env.nextWildLocatorId.Load())
+
+	if marshaler, ok := env.rng.(encoding.BinaryMarshaler); ok {
+		state, err := marshaler.MarshalBinary()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not save the random generator state:", err)
+		}
+		snapshot.Rng = state
+	}
+
+	return snapshot
+}
+
+func saveCheckpoint(output io.Writer, path string, snapshot Snapshot) {
+	err := writeCheckpoint(path, snapshot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: could not write the checkpoint:", err)
+		return
+	}
+	fmt.Fprintln(output, "INFO: checkpoint written to", path)
+}
+
+// saveLayout puts the charging stations and the capacities of the lattice in
+// the snapshot, only the capacities that differ from the default are listed
+func (l *Lattice) saveLayout(snapshot *Snapshot) {
+	snapshot.ChargingStations = make([]PositionConfig, 0)
+	snapshot.Capacity = l.capacity
+	for y := 0; y < l.m; y++ {
+		for x := 0; x < l.n; x++ {
+			v := &l.vertices[y][x]
+			if v.charging {
+				snapshot.ChargingStations = append(snapshot.ChargingStations, PositionConfig{X: x, Y: y})
+			}
+			if v.capacity != l.capacity {
+				snapshot.Capacities = append(snapshot.Capacities, CapacityConfig{X: x, Y: y, Capacity: v.capacity})
+			}
+		}
+	}
+}
+
+// restoreLayout puts the charging stations and capacities of the checkpoint
+// in place of the ones from the config, it returns how many explorers fit on
+// the lattice
+func (l *Lattice) restoreLayout(snapshot Snapshot) int {
+	if snapshot.ChargingStations != nil {
+		for y := 0; y < l.m; y++ {
+			for x := 0; x < l.n; x++ {
+				l.vertices[y][x].charging = false
+			}
+		}
+		for _, station := range snapshot.ChargingStations {
+			l.vertices[station.Y][station.X].charging = true
+		}
+	}
+	if snapshot.Capacity > 0 {
+		l.capacity = snapshot.Capacity
+		for y := 0; y < l.m; y++ {
+			for x := 0; x < l.n; x++ {
+				l.vertices[y][x].capacity = snapshot.Capacity
+			}
+		}
+		for _, c := range snapshot.Capacities {
+			l.vertices[c.Y][c.X].capacity = c.Capacity
+		}
+	}
+	return l.totalCapacity()
+}
+
+func writeCheckpoint(path string, snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func ReadCheckpoint(path string) (Snapshot, error) {
+	snapshot := Snapshot{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+	if snapshot.N < 1 || snapshot.M < 1 {
+		return snapshot, fmt.Errorf("checkpoint %s has an invalid lattice size %dx%d", path, snapshot.N, snapshot.M)
+	}
+
+	err = checkSnapshot(snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("checkpoint %s %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// checkSnapshot makes sure the world of a snapshot can be put on the lattice
+func checkSnapshot(snapshot Snapshot) error {
+	inside := func(x, y int) bool {
+		return x >= 0 && x < snapshot.N && y >= 0 && y < snapshot.M
+	}
+	for _, station := range snapshot.ChargingStations {
+		if !inside(station.X, station.Y) {
+			return fmt.Errorf("has charging station (%d,%d) outside of the lattice", station.X, station.Y)
+		}
+	}
+	capacities := make(map[position]int)
+	for _, c := range snapshot.Capacities {
+		if !inside(c.X, c.Y) {
+			return fmt.Errorf("has the capacity of vertex (%d,%d) outside of the lattice", c.X, c.Y)
+		}
+		if c.Capacity < 1 {
+			return fmt.Errorf("has capacity %d of vertex (%d,%d), it has to be at least 1", c.Capacity, c.X, c.Y)
+		}
+		capacities[position{c.X, c.Y}] = c.Capacity
+	}
+
+	ids := make(map[int]bool)
+	occupants := make(map[position]int)
+	for _, e := range snapshot.Explorers {
+		if !inside(e.X, e.Y) {
+			return fmt.Errorf("has explorer %d outside of the lattice", e.Id)
+		}
+		if ids[e.Id] {
+			return fmt.Errorf("has explorer %d twice", e.Id)
+		}
+		ids[e.Id] = true
+		occupants[position{e.X, e.Y}]++
+	}
+	for _, h := range snapshot.Hazards {
+		if !inside(h.X, h.Y) {
+			return fmt.Errorf("has a hazard outside of the lattice")
+		}
+	}
+	wild := make(map[position]bool)
+	for _, w := range snapshot.WildLocators {
+		if !inside(w.X, w.Y) {
+			return fmt.Errorf("has a wild locator outside of the lattice")
+		}
+		// a wild locator takes the whole vertex
+		at := position{w.X, w.Y}
+		if wild[at] || occupants[at] > 0 {
+			return fmt.Errorf("has a wild locator on the occupied vertex (%d,%d)", w.X, w.Y)
+		}
+		wild[at] = true
+	}
+
+	if snapshot.Capacity == 0 {
+		// an older checkpoint, the capacities come from the config
+		return nil
+	}
+	for at, count := range occupants {
+		capacity, ok := capacities[at]
+		if !ok {
+			capacity = snapshot.Capacity
+		}
+		if count > capacity {
+			return fmt.Errorf("has %d explorers on vertex (%d,%d) with capacity %d", count, at.x, at.y, capacity)
+		}
+	}
+	return nil
+}
+
+// restoreSnapshot places everything from the snapshot on the lattice before
+// the vertex routines are started
+func restoreSnapshot(snapshot Snapshot, lattice *Lattice, explorerWg *sync.WaitGroup, explorerStats *ExplorerStats, wildLocatorWg *sync.WaitGroup, logQueue *LogQueue) {
+	restoreCounters(snapshot, lattice.env, explorerStats)
+
+	for _, h := range snapshot.Hazards {
+		v := &lattice.vertices[h.Y][h.X]
+		v.AttachLogger(logQueue)
+		v.hazardous = true
+		v.hazardLifeLeft = h.LifeLeft
+		v.LogHazardSpawned(h.LifeLeft)
+	}
+
+	for _, e := range snapshot.Explorers {
+		v := &lattice.vertices[e.Y][e.X]
+		v.AttachLogger(logQueue)
+		startExplorer(explorerWg, lattice, explorerStats, v, e.Id, restoredTeam(lattice.env, e), e.Energy, logQueue)
+	}
+
+	for _, w := range snapshot.WildLocators {
+		v := &lattice.vertices[w.Y][w.X]
+		v.AttachLogger(logQueue)
+		startWildLocator(wildLocatorWg, lattice, v, w.Id, w.LifeLeft, logQueue)
+	}
+}
+
+// restoreCounters brings back the random generator and the counters of a
+// checkpoint, the explorers of the checkpoint are counted as running
+func restoreCounters(snapshot Snapshot, env *Env, explorerStats *ExplorerStats) {
+	if unmarshaler, ok := env.rng.(encoding.BinaryUnmarshaler); ok && snapshot.Rng != nil {
+		err := unmarshaler.UnmarshalBinary(snapshot.Rng)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not restore the random generator state:", err)
+		}
+	}
+
+	explorerStats.mu.Lock()
+	explorerStats.nextId = snapshot.NextExplorerId
+	explorerStats.count += len(snapshot.Explorers)
+	explorerStats.mu.Unlock()
+	env.nextWildLocatorId.Store(int64(snapshot.LastWildId))
+}
+
+// restoredTeam finds the team of an explorer from a checkpoint
+func restoredTeam(env *Env, e ExplorerState) *Team {
+	team := env.findTeam(e.Team)
+	if team == nil {
+		fmt.Fprintf(os.Stderr, "WARNING: team %q of explorer %d is not configured, using %s\n", e.Team, e.Id, env.teams[0].name)
+		team = env.teams[0]
+	}
+	return team
+}
@@ -0,0 +1,94 @@
+package sim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointKeepsLayout(t *testing.T) {
+	config := DefaultConfig()
+	config.RandomChargingStations = 5
+	config.Capacity = 2
+	config.Capacities = []CapacityConfig{{X: 1, Y: 2, Capacity: 4}}
+	s, err := New(Options{N: 6, M: 4, Seed: 1, Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := writeCheckpoint(path, s.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another seed draws other random stations, the checkpoint has to win
+	resumed, err := New(Options{Seed: 2, Config: config, Resume: &snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			saved, restored := &s.lattice.vertices[y][x], &resumed.lattice.vertices[y][x]
+			if saved.charging != restored.charging {
+				t.Errorf("charging station at (%d,%d) is %v, was %v", x, y, restored.charging, saved.charging)
+			}
+			if saved.capacity != restored.capacity {
+				t.Errorf("capacity at (%d,%d) is %d, was %d", x, y, restored.capacity, saved.capacity)
+			}
+		}
+	}
+	if resumed.maxExplorers != s.maxExplorers {
+		t.Errorf("%d explorers fit after resume, %d before", resumed.maxExplorers, s.maxExplorers)
+	}
+}
+
+func TestReadCheckpointRejects(t *testing.T) {
+	cases := []struct {
+		name     string
+		snapshot string
+		err      string
+	}{
+		{
+			"duplicate explorer",
+			`{"n": 3, "m": 3, "capacity": 2, "explorers": [{"id": 1, "x": 0, "y": 0}, {"id": 1, "x": 2, "y": 2}]}`,
+			"explorer 1 twice",
+		},
+		{
+			"over capacity",
+			`{"n": 3, "m": 3, "capacity": 1, "explorers": [{"id": 1, "x": 1, "y": 1}, {"id": 2, "x": 1, "y": 1}]}`,
+			"2 explorers on vertex (1,1) with capacity 1",
+		},
+		{
+			"over a lowered capacity",
+			`{"n": 3, "m": 3, "capacity": 3, "capacities": [{"x": 1, "y": 1, "capacity": 1}], "explorers": [{"id": 1, "x": 1, "y": 1}, {"id": 2, "x": 1, "y": 1}]}`,
+			"2 explorers on vertex (1,1) with capacity 1",
+		},
+		{
+			"wild locator with an explorer",
+			`{"n": 3, "m": 3, "capacity": 2, "explorers": [{"id": 1, "x": 1, "y": 1}], "wildLocators": [{"id": 1, "x": 1, "y": 1}]}`,
+			"occupied vertex (1,1)",
+		},
+		{
+			"charging station outside",
+			`{"n": 3, "m": 3, "capacity": 1, "chargingStations": [{"x": 3, "y": 0}]}`,
+			"charging station (3,0) outside",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+			if err := os.WriteFile(path, []byte(c.snapshot), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadCheckpoint(path)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("got error %v, want one with %q", err, c.err)
+			}
+		})
+	}
+}
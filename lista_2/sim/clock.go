@@ -0,0 +1,224 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ClockMode says which logical clocks stamp the log events
+type ClockMode int
+
+const (
+	// ClocksLamport stamps every event with a Lamport time
+	ClocksLamport ClockMode = iota
+	// ClocksVector also stamps every event with a vector clock
+	ClocksVector
+	// ClocksNone leaves the events unstamped
+	ClocksNone
+)
+
+var clockModeNames = map[string]ClockMode{
+	"lamport": ClocksLamport,
+	"vector":  ClocksVector,
+	"none":    ClocksNone,
+}
+
+func (c ClockMode) String() string {
+	for name, mode := range clockModeNames {
+		if mode == c {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// ParseClockMode reads the name of a clock mode as given to -clocks
+func ParseClockMode(name string) (ClockMode, error) {
+	mode, ok := clockModeNames[name]
+	if !ok {
+		return ClocksNone, fmt.Errorf("unknown clocks %q, use lamport, vector or none", name)
+	}
+	return mode, nil
+}
+
+// Stamp is the reading of a logical clock attached to an event or a message.
+// Vector is only set when the run uses vector clocks.
+type Stamp struct {
+	Process string            `json:"process,omitempty"`
+	Lamport uint64            `json:"lamport,omitempty"`
+	Vector  map[string]uint64 `json:"vector,omitempty"`
+}
+
+func (s Stamp) String() string {
+	if s.Lamport == 0 {
+		return ""
+	}
+	if s.Vector == nil {
+		return fmt.Sprintf("<L:%d>", s.Lamport)
+	}
+	processes := make([]string, 0, len(s.Vector))
+	for process := range s.Vector {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	entries := make([]string, len(processes))
+	for i, process := range processes {
+		entries[i] = fmt.Sprintf("%s:%d", process, s.Vector[process])
+	}
+	return fmt.Sprintf("<L:%d V:{%s}>", s.Lamport, strings.Join(entries, " "))
+}
+
+// LogicalClock is the clock of one process of the simulation: a vertex, an
+// explorer or a wild locator. Only the routine running the process touches
+// it. Logging an event ticks the clock, sending a message carries its reading
+// and receiving one merges the reading of the sender. A nil clock, used when
+// the run has no clocks, does nothing.
+type LogicalClock struct {
+	process string
+	// kind is e or w for an explorer or a wild locator in a slot, empty for a
+	// vertex
+	kind    string
+	lamport uint64
+	vector  map[string]uint64
+}
+
+func newLogicalClock(process string, mode ClockMode) *LogicalClock {
+	switch mode {
+	case ClocksLamport:
+		return &LogicalClock{process: process}
+	case ClocksVector:
+		return &LogicalClock{process: process, vector: map[string]uint64{}}
+	default:
+		return nil
+	}
+}
+
+// resumeLogicalClock continues the clock of a process that moved over from
+// another worker. It takes a slot of this worker, the reading it came with
+// puts everything it did over there before it.
+func resumeLogicalClock(s Stamp, mode ClockMode, kind string, slots *processSlots) *LogicalClock {
+	c := newLogicalClock(s.Process, mode)
+	c.Receive(s)
+	return c.fork(kind, slots)
+}
+
+// Tick counts a new event of the process and returns the clock after it
+func (c *LogicalClock) Tick() Stamp {
+	if c == nil {
+		return Stamp{}
+	}
+	c.lamport++
+	if c.vector != nil {
+		c.vector[c.process]++
+	}
+	return c.Read()
+}
+
+// Read returns the clock without counting an event, to send with a message
+func (c *LogicalClock) Read() Stamp {
+	if c == nil {
+		return Stamp{}
+	}
+	s := Stamp{Process: c.process, Lamport: c.lamport}
+	if c.vector != nil {
+		s.Vector = make(map[string]uint64, len(c.vector))
+		for process, t := range c.vector {
+			s.Vector[process] = t
+		}
+	}
+	return s
+}
+
+// Receive merges the reading that came with a message. The event that
+// handles the message ticks the clock when it is logged.
+func (c *LogicalClock) Receive(s Stamp) {
+	if c == nil {
+		return
+	}
+	if s.Lamport > c.lamport {
+		c.lamport = s.Lamport
+	}
+	if c.vector == nil {
+		return
+	}
+	for process, t := range s.Vector {
+		if t > c.vector[process] {
+			c.vector[process] = t
+		}
+	}
+}
+
+// fork starts the clock of a process spawned by this one in a free slot of
+// the kind. The entry of the slot goes on from where the last process in it
+// left it, so its events come after the ones of the last process.
+func (c *LogicalClock) fork(kind string, slots *processSlots) *LogicalClock {
+	if c == nil {
+		return nil
+	}
+	name, at := slots.take(kind)
+	child := &LogicalClock{process: name, kind: kind, lamport: c.lamport}
+	if c.vector != nil {
+		child.vector = make(map[string]uint64, len(c.vector)+1)
+		for process, t := range c.vector {
+			child.vector[process] = t
+		}
+		if at > child.vector[name] {
+			child.vector[name] = at
+		}
+	}
+	return child
+}
+
+// end gives the slot back when the process is over, the clock isn't used
+// after that
+func (c *LogicalClock) end(slots *processSlots) {
+	if c == nil || c.kind == "" {
+		return
+	}
+	slots.give(c.kind, c.process, c.vector[c.process])
+}
+
+// processSlots names the explorers and wild locators of a worker. A process
+// takes a free slot and gives it back when it ends, so a vector has an entry
+// for every vertex and for the most processes alive at once, not one for
+// every explorer there ever was. Workers of a distributed run number their
+// slots like their ids, so the names stay unique.
+type processSlots struct {
+	mu    sync.Mutex
+	first int
+	step  int
+	next  map[string]int
+	free  map[string][]string
+	// left is where the entry of a free slot was left
+	left map[string]uint64
+}
+
+func newProcessSlots(first, step int) *processSlots {
+	return &processSlots{first: first, step: step, next: map[string]int{}, free: map[string][]string{}, left: map[string]uint64{}}
+}
+
+// take hands out a free slot of the kind and where its entry was left
+func (p *processSlots) take(kind string) (string, uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if free := p.free[kind]; len(free) > 0 {
+		name := free[len(free)-1]
+		p.free[kind] = free[:len(free)-1]
+		return name, p.left[name]
+	}
+	slot, ok := p.next[kind]
+	if !ok {
+		slot = p.first
+	}
+	p.next[kind] = slot + p.step
+	return fmt.Sprintf("%s%d", kind, slot), 0
+}
+
+func (p *processSlots) give(kind, name string, at uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free[kind] = append(p.free[kind], name)
+	p.left[name] = at
+}
@@ -0,0 +1,41 @@
+package sim
+
+import "testing"
+
+func TestForkReusesSlots(t *testing.T) {
+	slots := newProcessSlots(0, 1)
+	vertex := newLogicalClock("v0", ClocksVector)
+
+	var last uint64
+	for i := 0; i < 1000; i++ {
+		vertex.Tick()
+		explorer := vertex.fork("e", slots)
+		if i > 0 && explorer.vector[explorer.process] != last {
+			t.Fatalf("explorer %d starts its slot at %d, the last one left it at %d", i, explorer.vector[explorer.process], last)
+		}
+		explorer.Tick()
+		explorer.Tick()
+		last = explorer.vector[explorer.process]
+		vertex.Receive(explorer.Read())
+		explorer.end(slots)
+	}
+	if len(vertex.vector) != 2 {
+		t.Errorf("vector has %d entries after 1000 explorers one after another, want the vertex and one slot: %v", len(vertex.vector), vertex.vector)
+	}
+}
+
+func TestForkSlotsOfWorkers(t *testing.T) {
+	vertex := newLogicalClock("v0", ClocksVector)
+	first, second := newProcessSlots(1, 2), newProcessSlots(0, 2)
+
+	names := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		for _, slots := range []*processSlots{first, second} {
+			name := vertex.fork("e", slots).process
+			if names[name] {
+				t.Errorf("two explorers alive at once are both %s", name)
+			}
+			names[name] = true
+		}
+	}
+}
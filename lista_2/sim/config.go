@@ -0,0 +1,389 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Config is read from the json file given with -config, every field that is
+// left out keeps its default
+type Config struct {
+	Teams                  []TeamConfig      `json:"teams"`
+	Energy                 EnergyConfig      `json:"energy"`
+	ChargingStations       []PositionConfig  `json:"chargingStations"`
+	RandomChargingStations int               `json:"randomChargingStations"`
+	WildLocators           WildLocatorConfig `json:"wildLocators"`
+	Sensing                SensingConfig     `json:"sensing"`
+	Capacity               int               `json:"capacity"`
+	Capacities             []CapacityConfig  `json:"capacities"`
+	// Drift makes every edge along it one way, like a conveyor, it is N, S,
+	// E or W
+	Drift  string       `json:"drift"`
+	OneWay []EdgeConfig `json:"oneWay"`
+}
+
+// EdgeConfig makes the edge from (x,y) to its neighbour in the direction To
+// one way, nothing can cross it the other way. It overrides the drift.
+type EdgeConfig struct {
+	X  int    `json:"x"`
+	Y  int    `json:"y"`
+	To string `json:"to"`
+}
+
+// CapacityConfig overrides the capacity of one vertex, the capacity is the
+// number of explorers that can stay on the vertex at the same time
+type CapacityConfig struct {
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	Capacity int `json:"capacity"`
+}
+
+// SensingConfig lets explorers probe a neighbour before moving there and skip
+// it when the probe reports a hazard. Noise is the probability that a probe
+// reports the opposite of the truth.
+type SensingConfig struct {
+	Enabled bool    `json:"enabled"`
+	Noise   float64 `json:"noise"`
+}
+
+// WildLocatorConfig turns on roaming, where wild locators walk on their own
+// with the given move rate instead of only moving when evicted
+type WildLocatorConfig struct {
+	Roaming  bool    `json:"roaming"`
+	MoveRate float64 `json:"moveRate"`
+}
+
+// EnergyConfig describes the energy budget of explorers, a capacity of 0
+// turns energy off and explorers never get exhausted
+type EnergyConfig struct {
+	Capacity   float64 `json:"capacity"`
+	MoveCost   float64 `json:"moveCost"`
+	TickCost   float64 `json:"tickCost"`
+	ChargeRate float64 `json:"chargeRate"`
+}
+
+type PositionConfig struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type TeamConfig struct {
+	Name        string   `json:"name"`
+	MoveRate    float64  `json:"moveRate"`
+	TickTime    Duration `json:"tickTime"`
+	SpawnWeight float64  `json:"spawnWeight"`
+	Colour      string   `json:"colour"`
+}
+
+// UnmarshalJSON gives the fields a team leaves out the rates of the default
+// team, a rate or weight that is given as 0 stays 0
+func (t *TeamConfig) UnmarshalJSON(data []byte) error {
+	type plain TeamConfig
+	team := plain{MoveRate: moveExplorerRate, TickTime: Duration(tickTime), SpawnWeight: 1}
+	err := json.Unmarshal(data, &team)
+	if err != nil {
+		return err
+	}
+	*t = TeamConfig(team)
+	return nil
+}
+
+// Duration is a time.Duration written as a string like "50ms" in the config
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Teams:    []TeamConfig{{Name: "default", MoveRate: moveExplorerRate, TickTime: Duration(tickTime), SpawnWeight: 1}},
+		Capacity: 1,
+	}
+}
+
+func ReadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	// the teams of the file replace the default team instead of being
+	// decoded on top of it
+	defaultTeams := config.Teams
+	config.Teams = nil
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("config %s: %w", path, err)
+	}
+	if config.Teams == nil {
+		config.Teams = defaultTeams
+	}
+
+	if len(config.Teams) == 0 {
+		return config, fmt.Errorf("config %s: at least one team is needed", path)
+	}
+	names := make(map[string]bool)
+	for i := range config.Teams {
+		team := &config.Teams[i]
+		if team.Name == "" {
+			team.Name = fmt.Sprint("team", i+1)
+		}
+		if names[team.Name] {
+			return config, fmt.Errorf("config %s: team %s is defined twice", path, team.Name)
+		}
+		names[team.Name] = true
+		if team.TickTime <= 0 {
+			team.TickTime = Duration(tickTime)
+		}
+		if team.MoveRate < 0 || team.MoveRate > 1 {
+			return config, fmt.Errorf("config %s: move rate of team %s is not between 0 and 1", path, team.Name)
+		}
+		if team.SpawnWeight < 0 {
+			return config, fmt.Errorf("config %s: spawn weight of team %s is negative", path, team.Name)
+		}
+		if _, ok := teamColours[team.Colour]; !ok {
+			return config, fmt.Errorf("config %s: unknown colour %s of team %s", path, team.Colour, team.Name)
+		}
+	}
+
+	energy := config.Energy
+	if energy.Capacity < 0 || energy.MoveCost < 0 || energy.TickCost < 0 || energy.ChargeRate < 0 {
+		return config, fmt.Errorf("config %s: energy values can't be negative", path)
+	}
+	if config.WildLocators.MoveRate < 0 || config.WildLocators.MoveRate > 1 {
+		return config, fmt.Errorf("config %s: wild locator move rate is not between 0 and 1", path)
+	}
+	if config.Sensing.Noise < 0 || config.Sensing.Noise > 1 {
+		return config, fmt.Errorf("config %s: sensing noise is not between 0 and 1", path)
+	}
+	if config.RandomChargingStations < 0 {
+		return config, fmt.Errorf("config %s: number of random charging stations can't be negative", path)
+	}
+	if config.Capacity < 1 {
+		return config, fmt.Errorf("config %s: vertex capacity has to be at least 1", path)
+	}
+	for _, c := range config.Capacities {
+		if c.Capacity < 1 {
+			return config, fmt.Errorf("config %s: capacity of vertex (%d,%d) has to be at least 1", path, c.X, c.Y)
+		}
+	}
+
+	return config, nil
+}
+
+// placeChargingStations marks the configured vertices and the requested number
+// of random ones as charging stations, it has to run before the vertices start
+func (l *Lattice) placeChargingStations(config Config) error {
+	for _, station := range config.ChargingStations {
+		if station.X < 0 || station.X >= l.n || station.Y < 0 || station.Y >= l.m {
+			return fmt.Errorf("charging station (%d,%d) is outside of the %dx%d lattice", station.X, station.Y, l.n, l.m)
+		}
+		l.vertices[station.Y][station.X].charging = true
+	}
+
+	free := make([]*Vertex, 0, l.n*l.m)
+	for y := 0; y < l.m; y++ {
+		for x := 0; x < l.n; x++ {
+			if !l.vertices[y][x].charging {
+				free = append(free, &l.vertices[y][x])
+			}
+		}
+	}
+	if config.RandomChargingStations > len(free) {
+		return fmt.Errorf("can't place %d random charging stations on %d free vertices", config.RandomChargingStations, len(free))
+	}
+	for i := 0; i < config.RandomChargingStations; i++ {
+		j := i + l.env.rng.Intn(len(free)-i)
+		free[i], free[j] = free[j], free[i]
+		free[i].charging = true
+	}
+
+	return nil
+}
+
+// setCapacities gives every vertex the default capacity and then applies the
+// per vertex overrides, it returns how many explorers fit on the rows run by
+// this process
+func (l *Lattice) setCapacities(config Config) (int, error) {
+	l.capacity = config.Capacity
+	for y := 0; y < l.m; y++ {
+		for x := 0; x < l.n; x++ {
+			l.vertices[y][x].capacity = config.Capacity
+		}
+	}
+	for _, c := range config.Capacities {
+		if c.X < 0 || c.X >= l.n || c.Y < 0 || c.Y >= l.m {
+			return 0, fmt.Errorf("capacity of vertex (%d,%d) is given outside of the %dx%d lattice", c.X, c.Y, l.n, l.m)
+		}
+		l.vertices[c.Y][c.X].capacity = c.Capacity
+	}
+	return l.totalCapacity(), nil
+}
+
+// totalCapacity is how many explorers fit on the rows run by this process
+func (l *Lattice) totalCapacity() int {
+	total := 0
+	for y := l.firstRow; y < l.lastRow; y++ {
+		for x := 0; x < l.n; x++ {
+			total += l.vertices[y][x].capacity
+		}
+	}
+	return total
+}
+
+// setEdges blocks the way back over every one way edge, first along the drift
+// and then for the single edges
+func (l *Lattice) setEdges(config Config) error {
+	if config.Drift != "" {
+		drift, err := parseDirection(config.Drift)
+		if err != nil {
+			return fmt.Errorf("drift: %w", err)
+		}
+		for y := 0; y < l.m; y++ {
+			for x := 0; x < l.n; x++ {
+				l.vertices[y][x].block(opposite(drift))
+			}
+		}
+	}
+
+	given := make(map[[2]int]bool)
+	for _, e := range config.OneWay {
+		to, err := parseDirection(e.To)
+		if err != nil {
+			return fmt.Errorf("one way edge from (%d,%d): %w", e.X, e.Y, err)
+		}
+		x, y := step(e.X, e.Y, to)
+		if e.X < 0 || e.X >= l.n || e.Y < 0 || e.Y >= l.m || x < 0 || x >= l.n || y < 0 || y >= l.m {
+			return fmt.Errorf("one way edge from (%d,%d) to %s is outside of the %dx%d lattice", e.X, e.Y, to, l.n, l.m)
+		}
+		from, next := &l.vertices[e.Y][e.X], &l.vertices[y][x]
+		key := [2]int{from.id, next.id}
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if given[key] {
+			return fmt.Errorf("the edge between (%d,%d) and (%d,%d) is given twice", e.X, e.Y, x, y)
+		}
+		given[key] = true
+		delete(from.blocked, to)
+		next.block(opposite(to))
+	}
+	return nil
+}
+
+func (v *Vertex) block(direction LogDirection) {
+	if v.blocked == nil {
+		v.blocked = make(map[LogDirection]bool)
+	}
+	v.blocked[direction] = true
+}
+
+func parseDirection(name string) (LogDirection, error) {
+	switch name {
+	case "N":
+		return North, nil
+	case "S":
+		return South, nil
+	case "E":
+		return East, nil
+	case "W":
+		return West, nil
+	default:
+		return None, fmt.Errorf("unknown direction %q, use N, S, E or W", name)
+	}
+}
+
+var teamColours = map[string]string{
+	"":        "",
+	"red":     TERM_RED,
+	"green":   TERM_GREEN,
+	"yellow":  TERM_YELLOW,
+	"blue":    TERM_BLUE,
+	"magenta": TERM_MAGENTA,
+	"cyan":    TERM_CYAN,
+}
+
+// Team is a kind of explorer, every explorer belongs to exactly one team and
+// moves with the rate and tick period of its team
+type Team struct {
+	name        string
+	moveRate    atomicFloat
+	tickTime    time.Duration
+	spawnWeight float64
+	colour      string
+	spawned     atomic.Int64
+	moves       atomic.Int64
+	died        atomic.Int64
+	exhausted   atomic.Int64
+	probes      atomic.Int64
+}
+
+func NewTeams(configs []TeamConfig) []*Team {
+	teams := make([]*Team, len(configs))
+	for i, config := range configs {
+		teams[i] = &Team{
+			name:        config.Name,
+			tickTime:    time.Duration(config.TickTime),
+			spawnWeight: config.SpawnWeight,
+			colour:      teamColours[config.Colour],
+		}
+		teams[i].moveRate.Store(config.MoveRate)
+	}
+	return teams
+}
+
+func (t *Team) String() string {
+	return fmt.Sprintf("%s: spawned %d, moves %d, probes %d, died %d, exhausted %d, move rate %g, tick %s",
+		t.name, t.spawned.Load(), t.moves.Load(), t.probes.Load(), t.died.Load(), t.exhausted.Load(), t.moveRate.Load(), t.tickTime)
+}
+
+// pickTeam draws a team with probability proportional to its spawn weight
+func (env *Env) pickTeam() *Team {
+	total := 0.0
+	for _, team := range env.teams {
+		total += team.spawnWeight
+	}
+	if total <= 0 {
+		return env.teams[env.rng.Intn(len(env.teams))]
+	}
+
+	r := env.rng.Float64() * total
+	for _, team := range env.teams {
+		if r < team.spawnWeight {
+			return team
+		}
+		r -= team.spawnWeight
+	}
+	return env.teams[len(env.teams)-1]
+}
+
+func (env *Env) findTeam(name string) *Team {
+	for _, team := range env.teams {
+		if team.name == name {
+			return team
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package sim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadConfigTeamDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"teams": [{"name": "red"}, {"name": "blue", "tickTime": "20ms"}, {"name": "idle", "moveRate": 0, "spawnWeight": 0}]}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TeamConfig{
+		{Name: "red", MoveRate: moveExplorerRate, TickTime: Duration(tickTime), SpawnWeight: 1},
+		{Name: "blue", MoveRate: moveExplorerRate, TickTime: Duration(20 * time.Millisecond), SpawnWeight: 1},
+		{Name: "idle", MoveRate: 0, TickTime: Duration(tickTime), SpawnWeight: 0},
+	}
+	if len(read.Teams) != len(want) {
+		t.Fatalf("got %d teams, want %d: %+v", len(read.Teams), len(want), read.Teams)
+	}
+	for i := range want {
+		if read.Teams[i] != want[i] {
+			t.Errorf("team %d is %+v, want %+v", i, read.Teams[i], want[i])
+		}
+	}
+}
+
+func TestReadConfigKeepsDefaultTeam(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"capacity": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Teams) != 1 || read.Teams[0] != DefaultConfig().Teams[0] {
+		t.Errorf("teams are %+v, want the default team", read.Teams)
+	}
+}
@@ -0,0 +1,260 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const consoleHelp = `commands:
+  pause                         stop spawning, moving and the lifetimes
+  resume                        continue after pause
+  spawn explorer|hazard|locator X Y
+  hazard X Y                    same as spawn hazard X Y
+  locator X Y                   same as spawn locator X Y
+  set spawnRate|hazardRate|locatorRate|locatorMoveRate VALUE
+  set moveRate [TEAM] VALUE     move rate of one team or of all teams
+  stats                         print the state of the run
+  checkpoint [FILE]             write a checkpoint
+  quit                          end the run now`
+
+// Console accepts commands for a running simulation. Everything that changes
+// the world is sent to the vertex routines as messages, the console never
+// touches their state directly.
+type Console struct {
+	lattice        *Lattice
+	explorerStats  *ExplorerStats
+	tracker        *worldTracker
+	checkpointPath string
+	quit           chan bool
+	quitOnce       sync.Once
+}
+
+func NewConsole(lattice *Lattice, explorerStats *ExplorerStats, tracker *worldTracker, checkpointPath string) *Console {
+	return &Console{
+		lattice:        lattice,
+		explorerStats:  explorerStats,
+		tracker:        tracker,
+		checkpointPath: checkpointPath,
+		quit:           make(chan bool),
+	}
+}
+
+// Quit is closed when the quit command is received
+func (c *Console) Quit() <-chan bool {
+	return c.quit
+}
+
+// Serve executes commands read line by line from r and writes the answers to w
+func (c *Console) Serve(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fmt.Fprintln(w, c.Execute(line))
+	}
+}
+
+// Listen serves every connection made to the unix socket at path until the
+// returned listener is closed
+func (c *Console) Listen(path string) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				c.Serve(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return listener, nil
+}
+
+func (c *Console) Execute(line string) string {
+	fields := strings.Fields(line)
+	env := c.lattice.env
+
+	switch fields[0] {
+	case "help":
+		return consoleHelp
+	case "pause":
+		env.pause()
+		return "OK: paused"
+	case "resume":
+		env.resume()
+		return "OK: resumed"
+	case "spawn":
+		if len(fields) != 4 {
+			return "ERROR: usage: spawn explorer|hazard|locator X Y"
+		}
+		return c.spawn(fields[1], fields[2], fields[3])
+	case "hazard", "locator":
+		if len(fields) != 3 {
+			return fmt.Sprintf("ERROR: usage: %s X Y", fields[0])
+		}
+		return c.spawn(fields[0], fields[1], fields[2])
+	case "set":
+		if len(fields) == 4 && fields[1] == "moveRate" {
+			return c.setMoveRate(fields[2], fields[3])
+		}
+		if len(fields) != 3 {
+			return "ERROR: usage: set NAME VALUE"
+		}
+		if fields[1] == "moveRate" {
+			return c.setMoveRate("", fields[2])
+		}
+		param := env.params.Lookup(fields[1])
+		if param == nil {
+			return fmt.Sprint("ERROR: no such parameter: ", fields[1])
+		}
+		value, err := parseRate(fields[2])
+		if err != nil {
+			return fmt.Sprint("ERROR: ", err)
+		}
+		param.Store(value)
+		return fmt.Sprintf("OK: %s = %g", fields[1], value)
+	case "stats":
+		return c.stats()
+	case "checkpoint":
+		path := c.checkpointPath
+		if len(fields) > 1 {
+			path = fields[1]
+		}
+		if path == "" {
+			return "ERROR: usage: checkpoint FILE"
+		}
+		err := writeCheckpoint(path, takeSnapshot(c.tracker, c.lattice, c.explorerStats, env, env.clock.Now()))
+		if err != nil {
+			return fmt.Sprint("ERROR: could not write the checkpoint: ", err)
+		}
+		return fmt.Sprint("OK: checkpoint written to ", path)
+	case "quit":
+		c.quitOnce.Do(func() { close(c.quit) })
+		return "OK: quitting"
+	default:
+		return fmt.Sprintf("ERROR: unknown command %q, try help", fields[0])
+	}
+}
+
+func parseRate(arg string) (float64, error) {
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil || value < 0 || value > 1 {
+		return 0, fmt.Errorf("the value has to be a number between 0 and 1: %s", arg)
+	}
+	return value, nil
+}
+
+// setMoveRate changes the move rate of the named team or of every team when
+// no name is given
+func (c *Console) setMoveRate(teamName, arg string) string {
+	value, err := parseRate(arg)
+	if err != nil {
+		return fmt.Sprint("ERROR: ", err)
+	}
+
+	env := c.lattice.env
+	if teamName == "" {
+		for _, team := range env.teams {
+			team.moveRate.Store(value)
+		}
+		return fmt.Sprintf("OK: moveRate = %g for every team", value)
+	}
+
+	team := env.findTeam(teamName)
+	if team == nil {
+		return fmt.Sprint("ERROR: no such team: ", teamName)
+	}
+	team.moveRate.Store(value)
+	return fmt.Sprintf("OK: moveRate = %g for team %s", value, teamName)
+}
+
+func (c *Console) spawn(what, xArg, yArg string) string {
+	var msgType MessageType
+	switch what {
+	case "explorer":
+		msgType = MsgCtrlSpawnExplorer
+	case "hazard":
+		msgType = MsgCtrlSpawnHazard
+	case "locator":
+		msgType = MsgCtrlSpawnWildLocator
+	default:
+		return fmt.Sprint("ERROR: can't spawn ", what)
+	}
+
+	x, errX := strconv.Atoi(xArg)
+	y, errY := strconv.Atoi(yArg)
+	if errX != nil || errY != nil || x < 0 || x >= c.lattice.n || y < 0 || y >= c.lattice.m {
+		return fmt.Sprintf("ERROR: (%s,%s) is not a vertex of the %dx%d lattice", xArg, yArg, c.lattice.n, c.lattice.m)
+	}
+
+	env := c.lattice.env
+	response := make(chan Message)
+	if !env.trySendMessage(c.lattice.vertices[y][x].ctrl, Message{msgType: msgType, responseChannel: response, x: x, y: y}) {
+		return "ERROR: the simulation is shutting down"
+	}
+	res := env.tryRecievMessage(response)
+	if res == nil {
+		return "ERROR: the simulation is shutting down"
+	}
+	if res.msgType != MsgCtrlDone {
+		return fmt.Sprintf("ERROR: vertex (%d,%d) can't take a %s now", x, y, what)
+	}
+	return fmt.Sprintf("OK: %s spawned at (%d,%d)", what, x, y)
+}
+
+func (c *Console) stats() string {
+	env := c.lattice.env
+	snapshot := c.tracker.Snapshot(env.clock.Now())
+
+	c.explorerStats.mu.Lock()
+	count := c.explorerStats.count
+	nextId := c.explorerStats.nextId
+	c.explorerStats.mu.Unlock()
+
+	params := env.params
+	result := fmt.Sprintf("lattice: %dx%d paused: %t\n", c.lattice.n, c.lattice.m, env.isPaused())
+	result += fmt.Sprintf("explorers: %d next id: %d\n", count, nextId)
+	result += fmt.Sprintf("hazards: %d wild locators: %d\n", len(snapshot.Hazards), len(snapshot.WildLocators))
+	result += fmt.Sprintf("spawnRate: %g hazardRate: %g locatorRate: %g locatorMoveRate: %g roaming: %t",
+		params.spawnExplorerRate.Load(), params.spawnHazardRate.Load(), params.spawnWildLocatorRate.Load(),
+		params.wildLocatorMoveRate.Load(), env.roamingWildLocators)
+	for _, team := range env.teams {
+		result += fmt.Sprint("\nteam ", team)
+	}
+	return result
+}
+
+func startConsole(console *Console, where string) func() {
+	switch where {
+	case "":
+		return func() {}
+	case "stdin":
+		go console.Serve(os.Stdin, os.Stdout)
+		return func() {}
+	default:
+		listener, err := console.Listen(where)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not open the console socket:", err)
+			return func() {}
+		}
+		return func() {
+			listener.Close()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleCommands(t *testing.T) {
+	h := newHarness(t, 2, 1)
+	h.runVertex(0, 0)
+	h.started(1)
+	console := NewConsole(h.lattice, h.stats, newWorldTracker(2, 1), "")
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"pause", "OK: paused"},
+		{"stats", "lattice: 2x1 paused: true"},
+		{"resume", "OK: resumed"},
+		{"set spawnRate 0.25", "OK: spawnRate = 0.25"},
+		{"set locatorMoveRate 1", "OK: locatorMoveRate = 1"},
+		{"set spawnRate 2", "ERROR: the value has to be a number between 0 and 1: 2"},
+		{"set nothing 0.5", "ERROR: no such parameter: nothing"},
+		{"set spawnRate", "ERROR: usage: set NAME VALUE"},
+		{"set moveRate 0.5", "OK: moveRate = 0.5 for every team"},
+		{"set moveRate test 0.75", "OK: moveRate = 0.75 for team test"},
+		{"set moveRate nobody 0.75", "ERROR: no such team: nobody"},
+		{"spawn hazard 0 0", "OK: hazard spawned at (0,0)"},
+		{"spawn explorer 0 0", "ERROR: vertex (0,0) can't take a explorer now"},
+		{"locator 2 0", "ERROR: (2,0) is not a vertex of the 2x1 lattice"},
+		{"spawn dragon 0 0", "ERROR: can't spawn dragon"},
+		{"hazard 0", "ERROR: usage: hazard X Y"},
+		{"checkpoint", "ERROR: usage: checkpoint FILE"},
+		{"jump", `ERROR: unknown command "jump", try help`},
+		{"quit", "OK: quitting"},
+		{"quit", "OK: quitting"},
+	}
+	for _, test := range tests {
+		got := console.Execute(test.line)
+		if !strings.HasPrefix(got, test.want) {
+			t.Errorf("%q answered %q, want %q", test.line, got, test.want)
+		}
+	}
+
+	if h.env.params.spawnExplorerRate.Load() != 0.25 || h.team.moveRate.Load() != 0.75 {
+		t.Errorf("the rates weren't set: spawnRate %g, moveRate %g", h.env.params.spawnExplorerRate.Load(), h.team.moveRate.Load())
+	}
+	h.await(LogMsgHazardSpawned)
+	select {
+	case <-console.Quit():
+	default:
+		t.Error("quit didn't close the quit channel")
+	}
+}
+
+func TestPauseStopsActiveTime(t *testing.T) {
+	h := newHarness(t, 1, 1)
+	start := h.env.activeNow()
+	h.clock.Advance(time.Second)
+	h.env.pause()
+	h.env.pause()
+	h.clock.Advance(time.Minute)
+	if got := h.env.activeNow().Sub(start); got != time.Second {
+		t.Errorf("paused run is %v in, want 1s", got)
+	}
+	if left := h.env.timeLeft(start.Add(time.Second + time.Millisecond)); left != tickTime {
+		t.Errorf("paused run looks again in %v, want a tick", left)
+	}
+	h.env.resume()
+	h.env.resume()
+	h.clock.Advance(time.Second)
+	if got := h.env.activeNow().Sub(start); got != 2*time.Second {
+		t.Errorf("resumed run is %v in, want 2s", got)
+	}
+	if left := h.env.timeLeft(start.Add(time.Second)); left != 0 {
+		t.Errorf("a life that ended has %v left", left)
+	}
+}
+
+func TestPauseStopsLifetimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		place func(h *harness)
+		ends  LogType
+	}{
+		{"wild locator", func(h *harness) { h.placeWildLocator(0, 0, 1, 3*tickTime) }, LogMsgWildLocatorDied},
+		{"hazard", func(h *harness) { h.placeHazard(0, 0, 3*tickTime) }, LogMsgHazardDisappeared},
+	}
+	for _, test := range tests {
+		for workers, runner := range []string{"routines", "pool"} {
+			t.Run(test.name+" on the "+runner, func(t *testing.T) {
+				h := newPoolHarness(t, 1, 1, workers)
+				test.place(h)
+				h.env.pause()
+				h.runLattice()
+
+				h.clock.Advance(5 * tickTime)
+				h.refute(test.ends)
+				h.env.resume()
+				h.clock.Advance(2 * tickTime)
+				h.refute(test.ends)
+				// the life goes on for the time it had left at the pause
+				h.clock.Advance(tickTime)
+				h.await(test.ends)
+			})
+		}
+	}
+}
@@ -0,0 +1,886 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A distributed run splits the rows of the lattice between worker processes.
+// The coordinator starts the workers from its own executable, merges their
+// logs and runs the logger and the camera. Every worker runs the vertices of
+// its rows, the rows right above and below belong to the neighbouring workers
+// and are replaced by proxies that forward the messages sent to them over a
+// link to the worker that owns them. An explorer or a wild locator that is
+// let into a vertex of another region is started there by the worker of that
+// region, with the state it carried in its enter message, but only once the
+// proxy commits the move. A proxy that gave up waiting aborts it instead, so
+// the explorer never runs in both regions.
+
+// regionRows returns the rows run by the worker with the given index
+func regionRows(index, workers, m int) (int, int) {
+	return index * m / workers, (index + 1) * m / workers
+}
+
+func workerName(index int) string {
+	return fmt.Sprint("worker-", index)
+}
+
+// listen opens a listener and writes its address to dir/name.addr, so the
+// other processes can find it
+func listen(network, dir, name string) (net.Listener, error) {
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(dir, name+".sock")
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file is renamed into place, so a reader never sees half of an address
+	path := filepath.Join(dir, name+".addr")
+	err = os.WriteFile(path+".tmp", []byte(listener.Addr().String()), 0644)
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// dial waits until the listener called name has written its address and
+// connects to it
+func dial(network, dir, name string) (net.Conn, error) {
+	deadline := time.Now().Add(connectTimeout)
+	for {
+		address, err := os.ReadFile(filepath.Join(dir, name+".addr"))
+		if err == nil {
+			return net.Dial(network, string(address))
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s didn't start listening in %s", name, connectTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// wireMessage is a Message sent over a link, a request names the vertex it is
+// for and gets exactly one reply with the same id
+type wireMessage struct {
+	Id    int64 `json:"id"`
+	Reply bool  `json:"reply,omitempty"`
+	Bye   bool  `json:"bye,omitempty"`
+	// Commit or Abort follow a confirmed move, with the id of its request
+	Commit    bool          `json:"commit,omitempty"`
+	Abort     bool          `json:"abort,omitempty"`
+	Type      MessageType   `json:"type"`
+	X         int           `json:"x"`
+	Y         int           `json:"y"`
+	ExpId     int           `json:"expId,omitempty"`
+	Team      string        `json:"team,omitempty"`
+	Energy    float64       `json:"energy,omitempty"`
+	WildId    int           `json:"wildId,omitempty"`
+	LifeLeft  time.Duration `json:"lifeLeft,omitempty"`
+	Hazardous bool          `json:"hazardous,omitempty"`
+	Stamp     Stamp         `json:"stamp"`
+}
+
+var errLinkDown = errors.New("the link is down")
+
+// link is the connection between two neighbouring workers, both of them send
+// requests over it and answer the requests of the other one
+type link struct {
+	worker  int
+	conn    net.Conn
+	sendMu  sync.Mutex
+	encoder *json.Encoder
+	mu      sync.Mutex
+	nextId  int64
+	calls   map[int64]*pendingCall
+	// decisions wait for the commit or abort of the moves confirmed to the
+	// other worker, by the id of the request
+	decisions map[int64]chan wireMessage
+	down      bool
+	closing   atomic.Bool
+}
+
+type pendingCall struct {
+	request   wireMessage
+	done      chan wireMessage
+	abandoned bool
+}
+
+func newLink(worker int, conn net.Conn) *link {
+	return &link{worker: worker, conn: conn, encoder: json.NewEncoder(conn), calls: make(map[int64]*pendingCall), decisions: make(map[int64]chan wireMessage)}
+}
+
+func (l *link) send(msg wireMessage) error {
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+	return l.encoder.Encode(msg)
+}
+
+// call sends the request and waits for its reply, a move confirmed after the
+// timeout is aborted by serve
+func (l *link) call(request wireMessage, timeout time.Duration) (wireMessage, error) {
+	l.mu.Lock()
+	if l.down {
+		l.mu.Unlock()
+		return wireMessage{}, errLinkDown
+	}
+	l.nextId += 1
+	request.Id = l.nextId
+	c := &pendingCall{request: request, done: make(chan wireMessage, 1)}
+	l.calls[request.Id] = c
+	l.mu.Unlock()
+
+	err := l.send(request)
+	if err != nil {
+		l.mu.Lock()
+		delete(l.calls, request.Id)
+		l.mu.Unlock()
+		return wireMessage{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case reply, ok := <-c.done:
+		if !ok {
+			return wireMessage{}, errLinkDown
+		}
+		return reply, nil
+	case <-timer.C:
+		l.mu.Lock()
+		_, waiting := l.calls[request.Id]
+		c.abandoned = waiting
+		l.mu.Unlock()
+		if waiting {
+			return wireMessage{}, fmt.Errorf("worker %d didn't answer in %s", l.worker, timeout)
+		}
+		// the reply came in just now
+		reply, ok := <-c.done
+		if !ok {
+			return wireMessage{}, errLinkDown
+		}
+		return reply, nil
+	}
+}
+
+// serve reads from the link until it is closed, replies are handed to the
+// waiting calls and every request is handled in its own routine
+func (l *link) serve(handle func(*link, wireMessage)) {
+	decoder := json.NewDecoder(l.conn)
+	said := false
+	for {
+		var msg wireMessage
+		err := decoder.Decode(&msg)
+		if err != nil {
+			if !said && !l.closing.Load() {
+				fmt.Fprintf(os.Stderr, "WARNING: lost the link to worker %d: %v\n", l.worker, err)
+			}
+			break
+		}
+
+		switch {
+		case msg.Bye:
+			// the other worker is done, nothing it says from now on matters
+			said = true
+			l.markDown()
+		case msg.Reply:
+			l.mu.Lock()
+			c := l.calls[msg.Id]
+			delete(l.calls, msg.Id)
+			if c != nil && c.abandoned {
+				l.mu.Unlock()
+				l.dropLate(msg)
+				continue
+			}
+			l.mu.Unlock()
+			if c != nil {
+				c.done <- msg
+			}
+		case msg.Commit || msg.Abort:
+			l.mu.Lock()
+			decision := l.decisions[msg.Id]
+			delete(l.decisions, msg.Id)
+			l.mu.Unlock()
+			if decision != nil {
+				decision <- msg
+			} else if msg.Commit {
+				fmt.Fprintf(os.Stderr, "WARNING: worker %d committed a move after it was given up, the explorer or wild locator is lost\n", l.worker)
+			}
+		default:
+			go handle(l, msg)
+		}
+	}
+	l.markDown()
+}
+
+// markDown fails every call waiting for a reply and every call made later
+func (l *link) markDown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.down {
+		return
+	}
+	l.down = true
+	for id, c := range l.calls {
+		delete(l.calls, id)
+		close(c.done)
+	}
+	for id, decision := range l.decisions {
+		delete(l.decisions, id)
+		close(decision)
+	}
+}
+
+// expectDecision makes room for the commit or abort of a move confirmed to
+// the other worker, before the confirm is sent. The channel is closed when
+// the link goes down.
+func (l *link) expectDecision(id int64) chan wireMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	decision := make(chan wireMessage, 1)
+	if l.down {
+		close(decision)
+		return decision
+	}
+	l.decisions[id] = decision
+	return decision
+}
+
+// forgetDecision gives up on the decision, a commit that comes later is lost
+func (l *link) forgetDecision(id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.decisions, id)
+}
+
+// decide tells the other worker whether the move it confirmed goes ahead,
+// stamp is the clock of the explorer after it moved
+func (l *link) decide(id int64, commit bool, stamp Stamp) {
+	err := l.send(wireMessage{Id: id, Commit: commit, Abort: !commit, Stamp: stamp})
+	if err != nil && !l.closing.Load() {
+		fmt.Fprintf(os.Stderr, "WARNING: could not tell worker %d about move %d: %v\n", l.worker, id, err)
+	}
+}
+
+// dropLate aborts a move that was confirmed after the proxy had already
+// refused it, the other worker doesn't start the explorer or wild locator
+func (l *link) dropLate(reply wireMessage) {
+	switch reply.Type {
+	case MsgExplorerEnterConfirm, MsgWildLocatorEnterConfirm:
+		l.decide(reply.Id, false, Stamp{})
+	}
+}
+
+// Close says goodbye to the other worker and closes the connection
+func (l *link) Close() {
+	l.closing.Store(true)
+	l.send(wireMessage{Bye: true})
+	l.conn.Close()
+}
+
+// refusal is the answer to a message that couldn't be delivered, probes of an
+// unreachable vertex report a hazard so careful explorers stay away
+func refusal(msgType MessageType) Message {
+	switch msgType {
+	case MsgExplorerProbe:
+		return Message{msgType: MsgExplorerProbeResult, hazardous: true}
+	case MsgWildLocatorEnter:
+		return Message{msgType: MsgWildLocatorEnterDeny}
+	default:
+		return Message{msgType: MsgExplorerEnterDeny}
+	}
+}
+
+// Region is the part of the lattice run by one worker
+type Region struct {
+	index         int
+	lattice       *Lattice
+	up            *link
+	down          *link
+	coordinator   net.Conn
+	explorerWg    *sync.WaitGroup
+	explorerStats *ExplorerStats
+	wildLocatorWg *sync.WaitGroup
+	logQueue      *LogQueue
+}
+
+// connect opens the links to the coordinator and to the neighbouring workers
+func (r *Region) connect(network, dir string, workers int) error {
+	var listener net.Listener
+	var err error
+	if r.index < workers-1 {
+		// the worker below dials us
+		listener, err = listen(network, dir, workerName(r.index))
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+	}
+
+	r.coordinator, err = dial(network, dir, "coordinator")
+	if err != nil {
+		return err
+	}
+
+	if r.index > 0 {
+		conn, err := dial(network, dir, workerName(r.index-1))
+		if err != nil {
+			return err
+		}
+		r.up = newLink(r.index-1, conn)
+	}
+
+	if listener != nil {
+		accepted := make(chan net.Conn)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR: could not accept the worker below:", err)
+				conn = nil
+			}
+			accepted <- conn
+		}()
+		select {
+		case conn := <-accepted:
+			if conn == nil {
+				return fmt.Errorf("worker %d didn't connect", r.index+1)
+			}
+			r.down = newLink(r.index+1, conn)
+		case <-time.After(connectTimeout):
+			return fmt.Errorf("worker %d didn't connect in %s", r.index+1, connectTimeout)
+		}
+	}
+
+	for _, l := range []*link{r.up, r.down} {
+		if l != nil {
+			go l.serve(r.handleRequest)
+		}
+	}
+	return nil
+}
+
+func (r *Region) linkFor(y int) *link {
+	if y < r.lattice.firstRow {
+		return r.up
+	}
+	return r.down
+}
+
+// startProxies runs a proxy in place of every vertex of the rows right next
+// to the region
+func (r *Region) startProxies(wg *sync.WaitGroup) {
+	for _, y := range []int{r.lattice.firstRow - 1, r.lattice.lastRow} {
+		if y < 0 || y >= r.lattice.m {
+			continue
+		}
+		for x := 0; x < r.lattice.n; x++ {
+			wg.Add(1)
+			go func(v *Vertex) {
+				r.runProxy(v)
+				wg.Done()
+			}(&r.lattice.vertices[y][x])
+		}
+	}
+}
+
+// runProxy takes the messages sent to a vertex of another region and passes
+// them on to the worker of that region, one message at a time just as the
+// vertex itself would
+func (r *Region) runProxy(v *Vertex) {
+	env := r.lattice.env
+	ticker := env.clock.NewTicker(tickTime)
+	defer ticker.Stop()
+
+	for !env.shouldQuit() {
+		select {
+		case msg := <-v.in:
+			r.forward(v, msg)
+		case msg := <-v.inWild:
+			r.forward(v, msg)
+		case <-ticker.C():
+			// this ensures that the proxy doesn't hang after the end of the run
+		}
+	}
+}
+
+func (r *Region) forward(v *Vertex, msg Message) {
+	request := wireMessage{Type: msg.msgType, X: v.x, Y: v.y, ExpId: msg.expId, Energy: msg.energy, WildId: msg.wildId, LifeLeft: msg.lifeLeft, Stamp: msg.stamp}
+	if msg.team != nil {
+		request.Team = msg.team.name
+	}
+
+	env := r.lattice.env
+	l := r.linkFor(v.y)
+	response := refusal(msg.msgType)
+	reply, err := l.call(request, proxyTimeout)
+	if err == nil {
+		response = Message{msgType: reply.Type, hazardous: reply.Hazardous, stamp: reply.Stamp}
+	} else if err != errLinkDown {
+		fmt.Fprintln(os.Stderr, "WARNING:", err)
+	}
+
+	switch response.msgType {
+	case MsgExplorerEnterConfirm:
+		// the move is committed once the explorer has logged it, its clock
+		// goes over with the commit
+		ready := make(chan Message)
+		response.responseChannel = ready
+		var res *Message
+		if env.trySendMessage(msg.responseChannel, response) {
+			res = env.tryRecievMessage(ready)
+		}
+		if res == nil {
+			l.decide(reply.Id, false, Stamp{})
+			return
+		}
+		l.decide(reply.Id, true, res.stamp)
+	case MsgWildLocatorEnterConfirm:
+		l.decide(reply.Id, env.trySendMessage(msg.responseChannel, response), Stamp{})
+	default:
+		env.trySendMessage(msg.responseChannel, response)
+	}
+}
+
+// handleRequest delivers a message from a proxy of the other worker to the
+// vertex it is for and sends back the answer of the vertex. Explorers and
+// wild locators that were let in start running here.
+func (r *Region) handleRequest(l *link, request wireMessage) {
+	if !r.lattice.owns(request.Y) || request.X < 0 || request.X >= r.lattice.n {
+		fmt.Fprintln(os.Stderr, "ERROR: request for a vertex of another region:", request)
+		l.send(wireMessage{Id: request.Id, Reply: true, Type: refusal(request.Type).msgType})
+		return
+	}
+
+	env := r.lattice.env
+	v := &r.lattice.vertices[request.Y][request.X]
+	reply := make(chan Message)
+	msg := Message{msgType: request.Type, expId: request.ExpId, responseChannel: reply, stamp: request.Stamp}
+	if request.Team != "" {
+		msg.team = env.findTeam(request.Team)
+		if msg.team == nil {
+			fmt.Fprintf(os.Stderr, "WARNING: team %q of explorer %d is not configured, using %s\n", request.Team, request.ExpId, env.teams[0].name)
+			msg.team = env.teams[0]
+		}
+	}
+
+	target := v.in
+	if request.Type == MsgWildLocatorEnter {
+		target = v.inWild
+		msg.wildLocatorChannel = make(chan Message)
+	}
+
+	response := refusal(request.Type)
+	// a neighbour only gets in when the vertex is listening right away, just
+	// like explorers and wild locators of this region
+	if offer(target, msg, 10*time.Millisecond) {
+		if res := env.tryRecievMessage(reply); res != nil {
+			response = *res
+		}
+	}
+
+	var decision chan wireMessage
+	if response.msgType == MsgExplorerEnterConfirm || response.msgType == MsgWildLocatorEnterConfirm {
+		decision = l.expectDecision(request.Id)
+	}
+
+	err := l.send(wireMessage{Id: request.Id, Reply: true, Type: response.msgType, Hazardous: response.hazardous, Stamp: response.stamp})
+	if err != nil && !env.shouldQuit() {
+		fmt.Fprintf(os.Stderr, "WARNING: could not answer worker %d: %v\n", l.worker, err)
+	}
+	if decision == nil {
+		return
+	}
+	commit, committed := r.awaitDecision(l, request.Id, decision)
+
+	switch response.msgType {
+	case MsgExplorerEnterConfirm:
+		if !committed {
+			// the vertex lets go of the place it kept for the explorer
+			env.trySendMessage(response.responseChannel, Message{msgType: MsgExplorerEnterCancel, stamp: response.stamp})
+			return
+		}
+		explorer := Explorer{id: request.ExpId, team: msg.team, energy: request.Energy, x: v.x, y: v.y, lattice: r.lattice, env: env, self: make(chan Message)}
+		explorer.spendEnergy(env.energy.MoveCost)
+		// the explorer goes on with its clock from the other worker, it
+		// logged the move there before the commit
+		explorer.logical = resumeLogicalClock(commit.Stamp, env.clocks, "e", env.slots)
+		if response.responseChannel != nil {
+			env.trySendMessage(response.responseChannel, Message{msgType: MsgExplorerReady, stamp: explorer.logical.Read()})
+		}
+		r.explorerStats.mu.Lock()
+		r.explorerStats.count += 1
+		r.explorerStats.mu.Unlock()
+		runExplorer(r.explorerWg, r.explorerStats, explorer, r.logQueue)
+	case MsgWildLocatorEnterConfirm:
+		lifeLeft := request.LifeLeft
+		if lifeLeft <= 0 {
+			lifeLeft = time.Nanosecond
+		}
+		wildLocator := WildLocator{id: request.WildId, x: v.x, y: v.y, lattice: r.lattice, env: env, lifeTime: lifeLeft, self: msg.wildLocatorChannel, reply: make(chan Message)}
+		wildLocator.logical = resumeLogicalClock(request.Stamp, env.clocks, "w", env.slots)
+		wildLocator.logical.Receive(response.stamp)
+		if !committed {
+			// it leaves before it ever ran, answering the evictions asked meanwhile
+			wildLocator.leaveVertex(v.outWild, MsgWildLocatorLeave)
+			wildLocator.logical.end(env.slots)
+			return
+		}
+		wildLocator.logical.Tick()
+		runWildLocator(r.wildLocatorWg, wildLocator, r.logQueue)
+	}
+}
+
+// awaitDecision waits for the other worker to commit a move it was let in
+// for. An abort, a lost link, quit or no word in time all mean the move
+// didn't happen over there.
+func (r *Region) awaitDecision(l *link, id int64, decision chan wireMessage) (wireMessage, bool) {
+	env := r.lattice.env
+	deadline := time.NewTimer(proxyTimeout)
+	defer deadline.Stop()
+	for !env.shouldQuit() {
+		timer := time.NewTimer(10 * time.Millisecond)
+		select {
+		case msg, ok := <-decision:
+			timer.Stop()
+			return msg, ok && msg.Commit
+		case <-deadline.C:
+			timer.Stop()
+			l.forgetDecision(id)
+			return wireMessage{}, false
+		case <-timer.C:
+			// recheck quit variable
+		}
+	}
+	l.forgetDecision(id)
+	return wireMessage{}, false
+}
+
+// offer sends the message if the channel takes it within the given time
+func offer(channel chan<- Message, msg Message, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case channel <- msg:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (r *Region) Close() {
+	for _, l := range []*link{r.up, r.down} {
+		if l != nil {
+			l.Close()
+		}
+	}
+}
+
+// wireLog is a LogMessage sent from a worker to the coordinator
+type wireLog struct {
+	VertexId  int             `json:"vertexId"`
+	Type      LogType         `json:"type"`
+	Direction LogDirection    `json:"direction,omitempty"`
+	FromX     int             `json:"fromX"`
+	FromY     int             `json:"fromY"`
+	ToX       int             `json:"toX"`
+	ToY       int             `json:"toY"`
+	ExpId     int             `json:"expId,omitempty"`
+	WildId    int             `json:"wildId,omitempty"`
+	Team      string          `json:"team,omitempty"`
+	Energy    float64         `json:"energy,omitempty"`
+	Hazardous bool            `json:"hazardous,omitempty"`
+	Occupancy int             `json:"occupancy,omitempty"`
+	Capacity  int             `json:"capacity,omitempty"`
+	LifeTime  time.Duration   `json:"lifeTime,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	Lost      map[LogType]int `json:"lost,omitempty"`
+	Stamp     Stamp           `json:"stamp"`
+}
+
+// forwardLogs sends every log message of the worker to the coordinator, the
+// simulation keeps running when the coordinator is gone
+func forwardLogs(logChannel <-chan LogMessage, conn net.Conn) {
+	encoder := json.NewEncoder(conn)
+	var failed error
+	for log := range logChannel {
+		if failed != nil {
+			continue
+		}
+		msg := wireLog{
+			VertexId: log.vertexId, Type: log.logType, Direction: log.direction,
+			FromX: log.fromX, FromY: log.fromY, ToX: log.toX, ToY: log.toY,
+			ExpId: log.expId, WildId: log.wildId, Energy: log.energy, Hazardous: log.hazardous,
+			Occupancy: log.occupancy, Capacity: log.capacity, LifeTime: log.lifeTime, Timestamp: log.timestamp,
+			Lost: log.lost, Stamp: log.stamp,
+		}
+		if log.team != nil {
+			msg.Team = log.team.name
+		}
+		failed = encoder.Encode(msg)
+		if failed != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: could not send the logs to the coordinator:", failed)
+		}
+	}
+	conn.Close()
+}
+
+// receiveLogs reads the logs of one worker and passes them to the logger
+func receiveLogs(conn net.Conn, env *Env, logQueue *LogQueue) {
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg wireLog
+		err := decoder.Decode(&msg)
+		if err != nil {
+			return
+		}
+		log := LogMessage{
+			vertexId: msg.VertexId, logType: msg.Type, direction: msg.Direction,
+			fromX: msg.FromX, fromY: msg.FromY, toX: msg.ToX, toY: msg.ToY,
+			expId: msg.ExpId, wildId: msg.WildId, energy: msg.Energy, hazardous: msg.Hazardous,
+			occupancy: msg.Occupancy, capacity: msg.Capacity, lifeTime: msg.LifeTime, timestamp: msg.Timestamp,
+			lost: msg.Lost, stamp: msg.Stamp,
+		}
+		if msg.Team != "" {
+			log.team = env.findTeam(msg.Team)
+			if log.team == nil {
+				log.team = env.teams[0]
+			}
+		}
+		logQueue.Push(log)
+	}
+}
+
+// RunCoordinator starts opts.Workers worker processes, feeds their logs to
+// the logger and the camera and waits until every worker is done. The workers
+// get their settings as the flags of the lista_2 command line, appended to
+// opts.WorkerCommand or, without one, to this executable.
+func RunCoordinator(opts Options) error {
+	opts = opts.withDefaults()
+	n, m := opts.N, opts.M
+	env := newEnv(opts.Config, opts.Seed, opts.Clocks)
+	logConfig := opts.Log
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+
+	command := opts.WorkerCommand
+	if len(command) == 0 {
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		command = []string{executable}
+	}
+
+	dir, err := os.MkdirTemp("", "lattice-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := listen(opts.Network, dir, "coordinator")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	logQueue := NewLogQueue(logConfig.Buffer, logConfig.Overflow)
+	loggerDone := make(chan bool)
+	cameraChanel := make(chan CameraMessage, cameraBuffer)
+	cameraDone := make(chan bool)
+
+	cameraInput, stopObserving, err := startObserving(opts.ObserveAt, n, m, cameraChanel, output)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		loggerRun(logQueue.Messages(), cameraChanel, newWorldTracker(n, m), logConfig, env.clock, nil)
+		loggerDone <- true
+	}()
+
+	go func() {
+		if opts.Output != nil {
+			camera := NewCamera(cameraInput, n, m, opts.Output)
+			camera.Start()
+		} else {
+			for range cameraInput {
+			}
+		}
+		cameraDone <- true
+	}()
+
+	receiversWg := sync.WaitGroup{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			receiversWg.Add(1)
+			go func() {
+				receiveLogs(conn, env, logQueue)
+				receiversWg.Done()
+			}()
+		}
+	}()
+
+	workersWg := sync.WaitGroup{}
+	for i := 0; i < opts.Workers; i++ {
+		args := append([]string{}, command[1:]...)
+		args = append(args,
+			"-worker", strconv.Itoa(i), "-workers", strconv.Itoa(opts.Workers),
+			"-worker-dir", dir, "-network", opts.Network,
+			"-seed", strconv.FormatUint(opts.Seed, 10),
+			"-log-buffer", strconv.Itoa(logConfig.Buffer), "-log-overflow", logConfig.Overflow.String(),
+			"-clocks", opts.Clocks.String(), "-run-time", opts.RunTime.String(),
+		)
+		if opts.ConfigPath != "" {
+			args = append(args, "-config", opts.ConfigPath)
+		}
+		args = append(args, strconv.Itoa(n), strconv.Itoa(m))
+
+		cmd := exec.Command(command[0], args...)
+		// the output of the workers would get in the way of the camera
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		err := cmd.Start()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: could not start worker %d: %v\n", i, err)
+			continue
+		}
+
+		workersWg.Add(1)
+		go func(i int) {
+			err := cmd.Wait()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: worker %d failed: %v\n", i, err)
+			}
+			workersWg.Done()
+		}(i)
+	}
+	fmt.Fprintf(output, "INFO: started %d workers\n", opts.Workers)
+
+	workersWg.Wait()
+	fmt.Fprintln(output, "INFO: all workers finished")
+	listener.Close()
+
+	receiversWg.Wait()
+	logQueue.Close()
+
+	<-loggerDone
+	fmt.Fprintln(output, "INFO: logger routine finished")
+	logQueue.ReportDropped()
+
+	<-cameraDone
+	stopObserving()
+	fmt.Fprintln(output, "INFO: camera routine finished")
+	return nil
+}
+
+// RunWorker runs the vertices of region opts.WorkerIndex of a distributed run,
+// the coordinator starts it with the flags of the command line
+func RunWorker(opts Options) error {
+	opts = opts.withDefaults()
+	n, m := opts.N, opts.M
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	config, seed, logConfig := opts.Config, opts.Seed, opts.Log
+	index, workers := opts.WorkerIndex, opts.Workers
+	env := newEnv(config, seed, opts.Clocks)
+	lattice := CreateLattice(n, m, env)
+	lattice.firstRow, lattice.lastRow = regionRows(index, workers, m)
+	err := lattice.placeChargingStations(config)
+	if err != nil {
+		return err
+	}
+	maxExplorers, err := lattice.setCapacities(config)
+	if err != nil {
+		return err
+	}
+	err = lattice.setEdges(config)
+	if err != nil {
+		return err
+	}
+
+	// every worker places the same random charging stations, only then they
+	// start drawing their own numbers
+	env.rng = newLockedRng(seed + uint64(index) + 1)
+	env.wildLocatorIdStep = int64(workers)
+	env.nextWildLocatorId.Store(int64(index))
+	env.slots = newProcessSlots(index, workers)
+	explorerStats := ExplorerStats{count: 0, nextId: index + 1, firstId: index + 1, idStep: workers}
+
+	logQueue := NewLogQueue(logConfig.Buffer, logConfig.Overflow)
+	vertexWg := sync.WaitGroup{}
+	explorerWg := sync.WaitGroup{}
+	wildLocatorWg := sync.WaitGroup{}
+
+	region := &Region{
+		index:         index,
+		lattice:       &lattice,
+		explorerWg:    &explorerWg,
+		explorerStats: &explorerStats,
+		wildLocatorWg: &wildLocatorWg,
+		logQueue:      logQueue,
+	}
+	err = region.connect(opts.Network, opts.WorkerDir, workers)
+	if err != nil {
+		return err
+	}
+
+	loggerDone := make(chan bool)
+	go func() {
+		forwardLogs(logQueue.Messages(), region.coordinator)
+		loggerDone <- true
+	}()
+
+	for y := lattice.firstRow; y < lattice.lastRow; y++ {
+		for x := 0; x < n; x++ {
+			vertexWg.Add(1)
+			go func(v Vertex) {
+				v.run(&explorerWg, &explorerStats, &wildLocatorWg, maxExplorers, logQueue, &lattice)
+				vertexWg.Done()
+			}(lattice.vertices[y][x])
+		}
+	}
+	region.startProxies(&vertexWg)
+	fmt.Fprintf(output, "INFO: worker %d runs rows %d to %d\n", index, lattice.firstRow, lattice.lastRow-1)
+
+	time.Sleep(opts.RunTime)
+	env.quit.Store(true)
+
+	vertexWg.Wait()
+	explorerWg.Wait()
+	wildLocatorWg.Wait()
+	region.Close()
+
+	logQueue.Close()
+	<-loggerDone
+	logQueue.ReportDropped()
+
+	for _, team := range env.teams {
+		fmt.Fprintf(output, "INFO: worker %d team %s\n", index, team)
+	}
+	return nil
+}
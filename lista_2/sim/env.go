@@ -0,0 +1,247 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Env holds everything the routines of the simulation share with the outside
+// world: the source of time, the source of randomness, the quit and pause
+// flags and the rates that can be changed while the simulation runs.
+// Vertices, explorers and wild locators only reach them through Env, so a run
+// can be driven by a different clock or random generator.
+type Env struct {
+	clock   Clock
+	rng     Rng
+	quit    *atomic.Bool
+	paused  atomic.Bool
+	params  *Params
+	teams   []*Team
+	energy  EnergyConfig
+	sensing SensingConfig
+	clocks  ClockMode
+
+	roamingWildLocators bool
+	nextWildLocatorId   atomic.Int64
+	// workers of a distributed run hand out every n-th id, so ids stay unique
+	wildLocatorIdStep int64
+	// slots name the explorers and wild locators for the logical clocks
+	slots *processSlots
+	// pauses holds the time the run spent paused, the lifetimes and the run
+	// time only count the rest
+	pauses pauses
+}
+
+// Params are the rates of the simulation, they start at the defaults from
+// main and can be changed from the console during the run. Move rates belong
+// to the explorer teams.
+type Params struct {
+	spawnExplorerRate    atomicFloat
+	spawnHazardRate      atomicFloat
+	spawnWildLocatorRate atomicFloat
+	wildLocatorMoveRate  atomicFloat
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(value float64) {
+	f.bits.Store(math.Float64bits(value))
+}
+
+func NewParams() *Params {
+	params := &Params{}
+	params.spawnExplorerRate.Store(spawnExplorerRate)
+	params.spawnHazardRate.Store(spawnHazardRate)
+	params.spawnWildLocatorRate.Store(spawnWildLocatorRate)
+	params.wildLocatorMoveRate.Store(moveWildLocatorRate)
+	return params
+}
+
+// Lookup returns the rate with the given console name
+func (p *Params) Lookup(name string) *atomicFloat {
+	switch name {
+	case "spawnRate":
+		return &p.spawnExplorerRate
+	case "hazardRate":
+		return &p.spawnHazardRate
+	case "locatorRate":
+		return &p.spawnWildLocatorRate
+	case "locatorMoveRate":
+		return &p.wildLocatorMoveRate
+	default:
+		return nil
+	}
+}
+
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type Rng interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+func NewEnv(seed uint64, teams []*Team) *Env {
+	return &Env{clock: realClock{}, rng: newLockedRng(seed), quit: &atomic.Bool{}, params: NewParams(), teams: teams, wildLocatorIdStep: 1, slots: newProcessSlots(0, 1)}
+}
+
+func (env *Env) shouldQuit() bool {
+	return env.quit.Load()
+}
+
+func (env *Env) isPaused() bool {
+	return env.paused.Load()
+}
+
+type pauses struct {
+	mu    sync.Mutex
+	since time.Time
+	total time.Duration
+}
+
+// pause stops the spawns, the moves and the lifetimes
+func (env *Env) pause() {
+	env.pauses.mu.Lock()
+	defer env.pauses.mu.Unlock()
+	if env.paused.Load() {
+		return
+	}
+	env.pauses.since = env.clock.Now()
+	env.paused.Store(true)
+}
+
+func (env *Env) resume() {
+	env.pauses.mu.Lock()
+	defer env.pauses.mu.Unlock()
+	if !env.paused.Load() {
+		return
+	}
+	env.pauses.total += env.clock.Now().Sub(env.pauses.since)
+	env.paused.Store(false)
+}
+
+// activeNow is the time of the clock without the pauses, it stands still
+// while the run is paused. Lifetimes end at an active time.
+func (env *Env) activeNow() time.Time {
+	env.pauses.mu.Lock()
+	defer env.pauses.mu.Unlock()
+	if env.paused.Load() {
+		return env.pauses.since.Add(-env.pauses.total)
+	}
+	return env.clock.Now().Add(-env.pauses.total)
+}
+
+// timeLeft is what a timer for a life that ends at the active time ends has
+// to wait after it fired, 0 when the life is over. The pauses make up the
+// rest and a paused run looks again every tick.
+func (env *Env) timeLeft(ends time.Time) time.Duration {
+	left := ends.Sub(env.activeNow())
+	if left <= 0 {
+		return 0
+	}
+	if env.isPaused() && left < tickTime {
+		left = tickTime
+	}
+	return left
+}
+
+type realClock struct{}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{timer: time.NewTimer(d)}
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+// lockedRng is a seeded generator safe for use by many routines at once
+type lockedRng struct {
+	mu  sync.Mutex
+	src *rand.PCG
+	rng *rand.Rand
+}
+
+func newLockedRng(seed uint64) *lockedRng {
+	src := rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)
+	return &lockedRng{src: src, rng: rand.New(src)}
+}
+
+func (r *lockedRng) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+func (r *lockedRng) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.IntN(n)
+}
+
+func (r *lockedRng) MarshalBinary() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.src.MarshalBinary()
+}
+
+func (r *lockedRng) UnmarshalBinary(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.src.UnmarshalBinary(data)
+}
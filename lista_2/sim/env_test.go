@@ -0,0 +1,178 @@
+package sim
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock only moves when the test advances it, the tickers and timers
+// fire then like the real ones: at most one tick waits in the channel
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+}
+
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	when   time.Time
+	active bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), when: c.now.Add(d), active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the time forward and fires everything that got due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	tickers := c.tickers[:0]
+	for _, t := range c.tickers {
+		if t.stopped {
+			continue
+		}
+		for !t.next.After(c.now) {
+			fire(t.c, c.now)
+			t.next = t.next.Add(t.period)
+		}
+		tickers = append(tickers, t)
+	}
+	c.tickers = tickers
+
+	// the routines make a new timer every time they wait for a message, the
+	// stopped and fired ones are forgotten
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.active {
+			continue
+		}
+		if !t.when.After(c.now) {
+			fire(t.c, c.now)
+			t.active = false
+			continue
+		}
+		timers = append(timers, t)
+	}
+	c.timers = timers
+}
+
+// running tells how many tickers are running
+func (c *fakeClock) running() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	running := 0
+	for _, t := range c.tickers {
+		if !t.stopped {
+			running++
+		}
+	}
+	return running
+}
+
+func fire(c chan time.Time, now time.Time) {
+	select {
+	case c <- now:
+	default:
+	}
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.when = t.clock.now.Add(d)
+	if !active {
+		// a stopped or fired timer is forgotten by the clock
+		t.clock.timers = append(t.clock.timers, t)
+	}
+	t.active = true
+	return active
+}
+
+// scriptedRng hands out the scripted numbers in order and the fallback once
+// the script runs out
+type scriptedRng struct {
+	mu       sync.Mutex
+	floats   []float64
+	ints     []int
+	fallback float64
+}
+
+func (r *scriptedRng) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.floats) == 0 {
+		return r.fallback
+	}
+	f := r.floats[0]
+	r.floats = r.floats[1:]
+	return f
+}
+
+func (r *scriptedRng) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ints) == 0 {
+		return 0
+	}
+	i := r.ints[0] % n
+	r.ints = r.ints[1:]
+	return i
+}
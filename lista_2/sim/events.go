@@ -0,0 +1,115 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is a log event as subscribers see it
+type Event struct {
+	// Type is the name of the event as in -log-events, like explorerMoved
+	Type        string
+	Time        time.Time
+	Vertex      int
+	Direction   string
+	FromX       int
+	FromY       int
+	ToX         int
+	ToY         int
+	Explorer    int
+	WildLocator int
+	Team        string
+	Energy      float64
+	Hazardous   bool
+	Occupancy   int
+	Capacity    int
+	// Lost counts the events by type an eventsLost event stands for
+	Lost map[string]int
+	// Stamp is the logical clock of the process behind the event
+	Stamp Stamp
+}
+
+func toEvent(log LogMessage) Event {
+	event := Event{
+		Type: logTypeName(log.logType), Time: log.timestamp, Vertex: log.vertexId, Direction: log.direction.String(),
+		FromX: log.fromX, FromY: log.fromY, ToX: log.toX, ToY: log.toY,
+		Explorer: log.expId, WildLocator: log.wildId, Energy: log.energy, Hazardous: log.hazardous,
+		Occupancy: log.occupancy, Capacity: log.capacity, Stamp: log.stamp,
+	}
+	if log.team != nil {
+		event.Team = log.team.name
+	}
+	if log.lost != nil {
+		event.Lost = make(map[string]int)
+		for logType, count := range log.lost {
+			event.Lost[logTypeName(logType)] = count
+		}
+	}
+	return event
+}
+
+// eventHub hands the events of the logger to the subscribers
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers []chan Event
+	closed      bool
+	// dropped counts the subscribers that couldn't keep up
+	dropped int
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{}
+}
+
+func (h *eventHub) Subscribe() <-chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan Event, subscriberBuffer)
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers = append(h.subscribers, ch)
+	return ch
+}
+
+// Publish sends the event to every subscriber, a nil hub has none. A
+// subscriber that can't keep up is dropped instead of holding up the logger,
+// its channel is closed early.
+func (h *eventHub) Publish(log LogMessage) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subscribers) == 0 {
+		return
+	}
+	event := toEvent(log)
+	subscribers := h.subscribers[:0]
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- event:
+			subscribers = append(subscribers, ch)
+		default:
+			close(ch)
+			h.dropped += 1
+		}
+	}
+	h.subscribers = subscribers
+}
+
+func (h *eventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, ch := range h.subscribers {
+		close(ch)
+	}
+	h.subscribers = nil
+	if h.dropped > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: dropped %d event subscribers that couldn't keep up\n", h.dropped)
+	}
+}
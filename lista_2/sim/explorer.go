@@ -0,0 +1,328 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+type Explorer struct {
+	logger  *ExplorerLogger
+	env     *Env
+	id      int
+	team    *Team
+	energy  float64
+	lattice *Lattice
+	x       int
+	y       int
+	self    chan Message
+	current chan<- Message
+	north   chan<- Message
+	south   chan<- Message
+	east    chan<- Message
+	west    chan<- Message
+	logical *LogicalClock
+}
+
+func spawnExplorer(wg *sync.WaitGroup, lattice *Lattice, explorerStats *ExplorerStats, maxExplorers int, v *Vertex, logQueue *LogQueue) bool {
+	expId, ok := nextExplorerId(explorerStats, maxExplorers)
+	if !ok {
+		return false
+	}
+	startExplorer(wg, lattice, explorerStats, v, expId, lattice.env.pickTeam(), lattice.env.energy.Capacity, logQueue)
+	return true
+}
+
+// nextExplorerId counts one more explorer in explorerStats and hands out its
+// id, unless there are maxExplorers explorers already
+func nextExplorerId(explorerStats *ExplorerStats, maxExplorers int) (int, bool) {
+	explorerStats.mu.Lock()
+	defer explorerStats.mu.Unlock()
+	if explorerStats.count >= maxExplorers {
+		return 0, false
+	}
+
+	expId := explorerStats.nextId
+	explorerStats.nextId += explorerStats.idStep
+	if explorerStats.nextId >= 100 {
+		explorerStats.nextId = explorerStats.firstId
+	}
+	explorerStats.count += 1
+	return expId, true
+}
+
+// startExplorer places an explorer with the given id on the vertex and runs it,
+// the explorer has to be already counted in explorerStats
+func startExplorer(wg *sync.WaitGroup, lattice *Lattice, explorerStats *ExplorerStats, v *Vertex, expId int, team *Team, energy float64, logQueue *LogQueue) {
+	explorer := Explorer{id: expId, team: team, energy: energy, x: v.x, y: v.y, lattice: lattice, env: lattice.env, self: make(chan Message)}
+	v.addExplorer(expId)
+	team.spawned.Add(1)
+	v.LogExplorerSpawned(expId, team, energy)
+	explorer.logical = v.logical.fork("e", lattice.env.slots)
+	runExplorer(wg, explorerStats, explorer, logQueue)
+}
+
+// runExplorer runs an explorer that already stands on its vertex, the
+// explorer has to be already counted in explorerStats
+func runExplorer(wg *sync.WaitGroup, explorerStats *ExplorerStats, explorer Explorer, logQueue *LogQueue) {
+	wg.Add(1)
+
+	go func() {
+		// setup explorer and run it
+		explorer.updateChannels()
+		explorer.AttachLogger(logQueue)
+		explorer.run()
+
+		// cleanup after the finish
+		explorer.logical.end(explorer.env.slots)
+		explorerStats.mu.Lock()
+		explorerStats.count -= 1
+		explorerStats.mu.Unlock()
+
+		wg.Done()
+	}()
+}
+
+func (e *Explorer) run() {
+	ticker := e.env.clock.NewTicker(e.team.tickTime)
+	defer ticker.Stop()
+
+	for !e.env.shouldQuit() {
+		<-ticker.C()
+
+		if e.env.isPaused() {
+			continue
+		}
+
+		e.charge()
+		if !e.spendEnergy(e.env.energy.TickCost) {
+			e.exhaust()
+			return
+		}
+
+		if e.env.rng.Float64() < e.team.moveRate.Load() {
+			var moved bool
+			var alive bool
+			if e.env.sensing.Enabled {
+				alive, moved = e.tryCarefulMove()
+			} else {
+				alive, moved = e.tryMove()
+			}
+
+			if !alive {
+				break
+			}
+
+			if moved {
+				e.team.moves.Add(1)
+				e.env.trySendMessage(e.current, Message{msgType: MsgExplorerLeave, expId: e.id, team: e.team, stamp: e.logical.Read()})
+				if !e.lattice.owns(e.y) {
+					// the worker running that region took the explorer over
+					return
+				}
+				e.updateChannels()
+
+				if e.energy <= 0 && e.env.energy.Capacity > 0 {
+					// the move used up the last of the energy
+					e.exhaust()
+					return
+				}
+			}
+		}
+	}
+}
+
+// tryMove asks any neighbour that is free to listen to let the explorer in
+func (e *Explorer) tryMove() (bool, bool) {
+	msg := Message{msgType: MsgExplorerEnter, expId: e.id, team: e.team, responseChannel: e.self, energy: e.energy, stamp: e.logical.Read()}
+	select {
+	case e.north <- msg:
+		return e.handleResponse(North)
+	case e.south <- msg:
+		return e.handleResponse(South)
+	case e.east <- msg:
+		return e.handleResponse(East)
+	case e.west <- msg:
+		return e.handleResponse(West)
+	default:
+		// no neighbor is available, so we reset the timer
+		return true, false
+	}
+}
+
+// tryCarefulMove probes the neighbours in random order and asks the first one
+// that doesn't look hazardous to let the explorer in
+func (e *Explorer) tryCarefulMove() (bool, bool) {
+	directions := []LogDirection{North, South, East, West}
+	for i := len(directions) - 1; i > 0; i-- {
+		j := e.env.rng.Intn(i + 1)
+		directions[i], directions[j] = directions[j], directions[i]
+	}
+
+	for _, direction := range directions {
+		neighbour := e.neighbour(direction)
+		if neighbour == nil {
+			continue
+		}
+
+		hazardous, answered := e.probe(direction, neighbour)
+		if !answered || hazardous {
+			continue
+		}
+
+		msg := Message{msgType: MsgExplorerEnter, expId: e.id, team: e.team, responseChannel: e.self, energy: e.energy, stamp: e.logical.Read()}
+		select {
+		case neighbour <- msg:
+			return e.handleResponse(direction)
+		default:
+			// it got busy since the probe
+		}
+	}
+	return true, false
+}
+
+// probe asks the neighbour whether it is hazardous, the answer goes through
+// the noisy sensor of the explorer
+func (e *Explorer) probe(direction LogDirection, neighbour chan<- Message) (bool, bool) {
+	select {
+	case neighbour <- Message{msgType: MsgExplorerProbe, expId: e.id, team: e.team, responseChannel: e.self, stamp: e.logical.Read()}:
+	default:
+		return false, false
+	}
+
+	res := e.env.tryRecievMessage(e.self)
+	if res == nil {
+		return false, false
+	}
+	e.logical.Receive(res.stamp)
+	if res.msgType != MsgExplorerProbeResult {
+		fmt.Fprintln(os.Stderr, "ERROR: expected an answer to the probe:", res)
+		return false, false
+	}
+
+	hazardous := res.hazardous
+	if e.env.rng.Float64() < e.env.sensing.Noise {
+		hazardous = !hazardous
+	}
+	e.team.probes.Add(1)
+	e.LogExplorerProbed(direction, hazardous)
+	return hazardous, true
+}
+
+func (e *Explorer) neighbour(direction LogDirection) chan<- Message {
+	switch direction {
+	case North:
+		return e.north
+	case South:
+		return e.south
+	case East:
+		return e.east
+	case West:
+		return e.west
+	default:
+		return nil
+	}
+}
+
+// charge refills the energy of an explorer staying on a charging station
+func (e *Explorer) charge() {
+	energy := e.env.energy
+	if energy.Capacity <= 0 || !e.lattice.vertices[e.y][e.x].charging {
+		return
+	}
+	e.energy += energy.ChargeRate
+	if e.energy > energy.Capacity {
+		e.energy = energy.Capacity
+	}
+}
+
+// spendEnergy takes the cost from the energy of the explorer and reports
+// whether the explorer has any energy left
+func (e *Explorer) spendEnergy(cost float64) bool {
+	if e.env.energy.Capacity <= 0 {
+		return true
+	}
+	e.energy -= cost
+	return e.energy > 0
+}
+
+// exhaust ends an explorer that ran out of energy
+func (e *Explorer) exhaust() {
+	e.team.exhausted.Add(1)
+	e.LogExplorerExhausted()
+	e.env.trySendMessage(e.current, Message{msgType: MsgExplorerLeave, expId: e.id, team: e.team, stamp: e.logical.Read()})
+}
+
+func (e *Explorer) handleResponse(direction LogDirection) (bool, bool) {
+	moved := false
+
+	res := e.env.tryRecievMessage(e.self)
+
+	if res == nil {
+		return true, false
+	}
+	e.logical.Receive(res.stamp)
+
+	switch res.msgType {
+	case MsgExplorerEnterConfirm:
+		e.spendEnergy(e.env.energy.MoveCost)
+		e.LogExplorerMoved(direction)
+		if res.responseChannel != nil {
+			e.env.trySendMessage(res.responseChannel, Message{msgType: MsgExplorerReady, stamp: e.logical.Read()})
+		}
+		switch direction {
+		case North:
+			e.y -= 1
+		case South:
+			e.y += 1
+		case West:
+			e.x -= 1
+		case East:
+			e.x += 1
+		default:
+			fmt.Fprintln(os.Stderr, "ERROR: Incorrect direction parameter!")
+		}
+		moved = true
+	case MsgExplorerEnterHazard:
+		e.team.died.Add(1)
+		e.LogExplorerDied()
+		e.env.trySendMessage(e.current, Message{msgType: MsgExplorerLeave, expId: e.id, team: e.team, stamp: e.logical.Read()})
+		return false, moved
+	case MsgExplorerEnterDeny:
+		// I guess we couldn't enter XD
+		moved = false
+		return true, moved
+	default:
+		fmt.Fprintln(os.Stderr, "ERROR: this type of message should not be handled here:", res)
+	}
+	return true, moved
+}
+
+func (e *Explorer) updateChannels() {
+	v := &e.lattice.vertices[e.y][e.x]
+	e.current = v.out
+
+	if e.y > 0 && !v.blocked[North] {
+		e.north = e.lattice.vertices[e.y-1][e.x].in
+	} else {
+		e.north = nil
+	}
+
+	if e.y < e.lattice.m-1 && !v.blocked[South] {
+		e.south = e.lattice.vertices[e.y+1][e.x].in
+	} else {
+		e.south = nil
+	}
+
+	if e.x > 0 && !v.blocked[West] {
+		e.west = e.lattice.vertices[e.y][e.x-1].in
+	} else {
+		e.west = nil
+	}
+
+	if e.x < e.lattice.n-1 && !v.blocked[East] {
+		e.east = e.lattice.vertices[e.y][e.x+1].in
+	} else {
+		e.east = nil
+	}
+}
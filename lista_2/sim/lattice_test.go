@@ -0,0 +1,85 @@
+package sim
+
+import "testing"
+
+// offsets are where the neighbour in each direction is
+var offsets = map[LogDirection][2]int{North: {0, -1}, South: {0, 1}, East: {1, 0}, West: {-1, 0}}
+
+func TestCreateLattice(t *testing.T) {
+	shapes := []struct {
+		name string
+		n, m int
+	}{
+		{"1x1", 1, 1},
+		{"1xk", 1, 5},
+		{"kx1", 5, 1},
+		{"square", 4, 4},
+		{"wide", 6, 3},
+		{"tall", 3, 6},
+	}
+
+	for _, shape := range shapes {
+		t.Run(shape.name, func(t *testing.T) {
+			env := NewEnv(1, nil)
+			lattice := CreateLattice(shape.n, shape.m, env)
+			vertices := lattice.vertices
+			if lattice.n != shape.n || lattice.m != shape.m {
+				t.Fatalf("got a %dx%d lattice, want %dx%d", lattice.n, lattice.m, shape.n, shape.m)
+			}
+			if len(vertices) != shape.m {
+				t.Fatalf("got %d rows, want %d", len(vertices), shape.m)
+			}
+
+			// explorers enter a vertex through its in channel, so the channel tells whose it is
+			owner := make(map[chan Message]int)
+			for y := range vertices {
+				if len(vertices[y]) != shape.n {
+					t.Fatalf("row %d has %d vertices, want %d", y, len(vertices[y]), shape.n)
+				}
+				for x := range vertices[y] {
+					v := &vertices[y][x]
+					if v.x != x || v.y != y || v.id != y*shape.n+x {
+						t.Errorf("vertex at (%d,%d) has x=%d y=%d id=%d", x, y, v.x, v.y, v.id)
+					}
+					owner[v.in] = v.id
+				}
+			}
+
+			for y := range vertices {
+				for x := range vertices[y] {
+					// an explorer standing on the vertex sees its neighbours
+					e := Explorer{lattice: &lattice, env: env, x: x, y: y}
+					e.updateChannels()
+					if e.current != vertices[y][x].out {
+						t.Errorf("explorer on (%d,%d) leaves through another vertex", x, y)
+					}
+					sides := map[LogDirection]chan<- Message{North: e.north, South: e.south, East: e.east, West: e.west}
+					for _, direction := range []LogDirection{North, South, East, West} {
+						channel := sides[direction]
+						nx, ny := x+offsets[direction][0], y+offsets[direction][1]
+						outside := nx < 0 || nx >= shape.n || ny < 0 || ny >= shape.m
+						if outside {
+							if channel != nil {
+								t.Errorf("vertex (%d,%d) has a neighbour to the %s outside of the lattice", x, y, direction)
+							}
+							continue
+						}
+						if channel == nil {
+							t.Errorf("vertex (%d,%d) has no neighbour to the %s", x, y, direction)
+							continue
+						}
+						found := -1
+						for in, id := range owner {
+							if channel == in {
+								found = id
+							}
+						}
+						if want := ny*shape.n + nx; found != want {
+							t.Errorf("vertex (%d,%d) to the %s leads to %d, want %d", x, y, direction, found, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type LogMessage struct {
+	vertexId  int
+	logType   LogType
+	direction LogDirection
+	fromX     int
+	fromY     int
+	toX       int
+	toY       int
+	expId     int
+	wildId    int
+	team      *Team
+	energy    float64
+	hazardous bool
+	occupancy int
+	capacity  int
+	lifeTime  time.Duration
+	timestamp time.Time
+	// lost counts the events by type a LogMsgEventsLost marker stands for
+	lost map[LogType]int
+	// stamp is the logical clock of the process that logged the event
+	stamp Stamp
+}
+type LogType int
+
+const (
+	LogMsgExplorerSpawned LogType = iota
+	LogMsgExplorerMoved
+	LogMsgExplorerReceived
+	LogMsgExplorerLeft
+	LogMsgExplorerDied
+	LogMsgExplorerEnteredHazard
+	LogMsgHazardSpawned
+	LogMsgHazardDisappeared
+	LogMsgWildLocatorSpawned
+	LogMsgWildLocatorMoved
+	LogMsgWildLocatorDied
+	LogMsgExplorerExhausted
+	LogMsgChargingStation
+	LogMsgExplorerProbed
+	LogMsgEventsLost
+	LogMsgOneWayEdge
+)
+
+type LogDirection int
+
+const (
+	None LogDirection = iota
+	North
+	South
+	East
+	West
+)
+
+func (l LogDirection) String() string {
+	switch l {
+	case None:
+		return ""
+	case North:
+		return "N"
+	case South:
+		return "S"
+	case East:
+		return "E"
+	case West:
+		return "W"
+	default:
+		return "No such direction"
+	}
+}
+
+type VertexLogger struct {
+	logQueue *LogQueue
+	logical  *LogicalClock
+}
+
+func (l *VertexLogger) push(msg LogMessage) {
+	msg.stamp = l.logical.Tick()
+	l.logQueue.Push(msg)
+}
+
+type ExplorerLogger struct {
+	logQueue *LogQueue
+	logical  *LogicalClock
+}
+
+func (l *ExplorerLogger) push(msg LogMessage) {
+	msg.stamp = l.logical.Tick()
+	l.logQueue.Push(msg)
+}
+
+type WildLocatorLogger struct {
+	logQueue *LogQueue
+	logical  *LogicalClock
+}
+
+func (l *WildLocatorLogger) push(msg LogMessage) {
+	msg.stamp = l.logical.Tick()
+	l.logQueue.Push(msg)
+}
+
+func (v *Vertex) AttachLogger(logQueue *LogQueue) {
+	v.logger = &VertexLogger{logQueue: logQueue, logical: v.logical}
+}
+
+func (e *Explorer) AttachLogger(logQueue *LogQueue) {
+	e.logger = &ExplorerLogger{logQueue: logQueue, logical: e.logical}
+}
+
+func (w *WildLocator) AttachLogger(logQueue *LogQueue) {
+	w.logger = &WildLocatorLogger{logQueue: logQueue, logical: w.logical}
+}
+
+func (v Vertex) LogWildLocatorSpawned(wildId int, lifeTime time.Duration) {
+	if v.logger != nil {
+		v.logger.push(MakeLogMsgWildLocatorSpawned(v.id, v.x, v.y, wildId, lifeTime))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached to vertex on wildLocator spawned:", v)
+	}
+}
+
+func (w WildLocator) LogWildLocatorDied() {
+	if w.logger != nil {
+		w.logger.push(MakeLogMsgWildLocatorDied(w.id, w.x, w.y))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached to wildLocator on wildLocator Died:", w)
+	}
+}
+
+func (w WildLocator) LogWildLocatorMoved(direction LogDirection) {
+	if w.logger != nil {
+		switch direction {
+		case North:
+			w.logger.push(MakeLogMsgWildLocatorMoved(w.id, w.x, w.y, w.x, w.y-1, direction))
+		case South:
+			w.logger.push(MakeLogMsgWildLocatorMoved(w.id, w.x, w.y, w.x, w.y+1, direction))
+		case East:
+			w.logger.push(MakeLogMsgWildLocatorMoved(w.id, w.x, w.y, w.x+1, w.y, direction))
+		case West:
+			w.logger.push(MakeLogMsgWildLocatorMoved(w.id, w.x, w.y, w.x-1, w.y, direction))
+		default:
+			panic("Can't log wild locator moved with no direction")
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached to wildLocator on wild locator move: ", w)
+	}
+}
+
+func (v Vertex) LogExplorerSpawned(expId int, team *Team, energy float64) {
+	if v.logger != nil {
+		msg := MakeLogMsgExplorerSpawned(v.id, v.x, v.y, expId, team)
+		msg.energy = energy
+		msg.occupancy, msg.capacity = len(v.explorers), v.capacity
+		v.logger.push(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Spawned: ", expId)
+	}
+}
+
+func (e Explorer) LogExplorerMoved(direction LogDirection) {
+	if e.logger != nil {
+		var msg LogMessage
+		switch direction {
+		case North:
+			msg = MakeLogMsgExplorerMoved(e.x, e.y, e.x, e.y-1, e.id, e.team, direction)
+		case South:
+			msg = MakeLogMsgExplorerMoved(e.x, e.y, e.x, e.y+1, e.id, e.team, direction)
+		case East:
+			msg = MakeLogMsgExplorerMoved(e.x, e.y, e.x+1, e.y, e.id, e.team, direction)
+		case West:
+			msg = MakeLogMsgExplorerMoved(e.x, e.y, e.x-1, e.y, e.id, e.team, direction)
+		default:
+			panic("Can't log explorer send with no direction")
+		}
+		msg.energy = e.energy
+		e.logger.push(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Moved: ", e.id)
+	}
+}
+
+func (e Explorer) LogExplorerDied() {
+	if e.logger != nil {
+		e.logger.push(MakeLogMsgExplorerDied(e.id, e.x, e.y, e.team))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Died: ", e.id)
+	}
+}
+
+func (e Explorer) LogExplorerProbed(direction LogDirection, hazardous bool) {
+	if e.logger != nil {
+		var msg LogMessage
+		switch direction {
+		case North:
+			msg = MakeLogMsgExplorerProbed(e.x, e.y, e.x, e.y-1, e.id, e.team, direction)
+		case South:
+			msg = MakeLogMsgExplorerProbed(e.x, e.y, e.x, e.y+1, e.id, e.team, direction)
+		case East:
+			msg = MakeLogMsgExplorerProbed(e.x, e.y, e.x+1, e.y, e.id, e.team, direction)
+		case West:
+			msg = MakeLogMsgExplorerProbed(e.x, e.y, e.x-1, e.y, e.id, e.team, direction)
+		default:
+			panic("Can't log explorer probe with no direction")
+		}
+		msg.hazardous = hazardous
+		e.logger.push(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Probed: ", e.id)
+	}
+}
+
+func (e Explorer) LogExplorerExhausted() {
+	if e.logger != nil {
+		e.logger.push(MakeLogMsgExplorerExhausted(e.id, e.x, e.y, e.team))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Exhausted: ", e.id)
+	}
+}
+
+func (v Vertex) LogChargingStation() {
+	if v.logger != nil {
+		v.logger.push(MakeLogMsgChargingStation(v.id, v.x, v.y))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on Charging Station")
+	}
+}
+
+func (v Vertex) LogOneWayEdge(direction LogDirection) {
+	if v.logger != nil {
+		toX, toY := step(v.x, v.y, direction)
+		v.logger.push(MakeLogMsgOneWayEdge(v.id, v.x, v.y, toX, toY, direction))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on One Way Edge")
+	}
+}
+
+func (v Vertex) LogExplorerReceived(expId int, team *Team) {
+	if v.logger != nil {
+		msg := MakeLogMsgExplorerReceived(v.id, v.x, v.y, expId, team)
+		msg.occupancy, msg.capacity = len(v.explorers), v.capacity
+		v.logger.push(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Received: ", expId)
+	}
+}
+
+func (v Vertex) LogExplorerLeft(expId int, team *Team) {
+	if v.logger != nil {
+		msg := MakeLogMsgExplorerLeft(v.id, v.x, v.y, expId, team)
+		msg.occupancy, msg.capacity = len(v.explorers), v.capacity
+		v.logger.push(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Received: ", expId)
+	}
+}
+
+func (v Vertex) LogMsgExplorerEnteredHazard(expId int, team *Team) {
+	if v.logger != nil {
+		v.logger.push(MakeLogMsgExplorerEnteredHazard(v.id, v.x, v.y, expId, team))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on explorer Entered Hazard: ", expId)
+	}
+}
+
+func (v Vertex) LogHazardSpawned(lifeTime time.Duration) {
+	if v.logger != nil {
+		v.logger.push(MakeLogMsgHazardSpawned(v.id, v.x, v.y, lifeTime))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on Hazard Spawned")
+	}
+}
+
+func (v Vertex) LogHazardDisappeared() {
+	if v.logger != nil {
+		v.logger.push(MakeLogMsgHazardDisappeared(v.id, v.x, v.y))
+	} else {
+		fmt.Fprintln(os.Stderr, "ERROR: no logger attached on Hazard Disapeard")
+	}
+}
+
+// TODO: fix names of toX fromX
+func (l LogMessage) String() string {
+	result := fmt.Sprintf("ID: %2d [%s] ", l.vertexId, l.timestamp.Format(time.StampMicro))
+	switch l.logType {
+	case LogMsgExplorerSpawned:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d)", l.expId, "spawned at", l.fromY, l.fromX)
+	case LogMsgExplorerMoved:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d) %2s (%2d,%2d) [%s]", l.expId, "moved from", l.fromY, l.fromX, "to", l.toY, l.toX, l.direction)
+	case LogMsgExplorerReceived:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d)", l.expId, "received at", l.toY, l.toX)
+	case LogMsgExplorerLeft:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d)", l.expId, "left from", l.fromY, l.fromX)
+	case LogMsgHazardSpawned:
+		result += fmt.Sprintf("HAZARD:  %15s (%2d,%2d)", "spawned at", l.toY, l.toX)
+	case LogMsgHazardDisappeared:
+		result += fmt.Sprintf("HAZARD:  %15s (%2d,%2d)", "disappeared at", l.toY, l.toX)
+	case LogMsgExplorerEnteredHazard:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d)", l.expId, "entered hazard", l.toY, l.toX)
+	case LogMsgExplorerDied:
+		result += fmt.Sprintf("E-ID: %2d %15s", l.expId, "died")
+	case LogMsgWildLocatorSpawned:
+		result += fmt.Sprintf("W-ID: %2d %15s (%2d,%2d)", l.wildId, "spawned at", l.fromY, l.fromX)
+	case LogMsgWildLocatorMoved:
+		result += fmt.Sprintf("W-ID: %2d %15s (%2d,%2d) %2s (%2d,%2d) [%s]", l.wildId, "moved from", l.fromY, l.fromX, "to", l.toY, l.toX, l.direction)
+	case LogMsgWildLocatorDied:
+		result += fmt.Sprintf("W-ID: %2d %15s", l.wildId, "died")
+	case LogMsgExplorerExhausted:
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d)", l.expId, "exhausted at", l.toY, l.toX)
+	case LogMsgChargingStation:
+		result += fmt.Sprintf("CHARGE:  %15s (%2d,%2d)", "station at", l.toY, l.toX)
+	case LogMsgOneWayEdge:
+		result += fmt.Sprintf("EDGE:    %15s (%2d,%2d) %2s (%2d,%2d) [%s]", "one way from", l.fromY, l.fromX, "to", l.toY, l.toX, l.direction)
+	case LogMsgExplorerProbed:
+		reading := "clear"
+		if l.hazardous {
+			reading = "hazard"
+		}
+		result += fmt.Sprintf("E-ID: %2d %15s (%2d,%2d) %2s (%2d,%2d) [%s] %s", l.expId, "probed from", l.fromY, l.fromX, "at", l.toY, l.toX, l.direction, reading)
+	case LogMsgEventsLost:
+		total := 0
+		for _, count := range l.lost {
+			total += count
+		}
+		result += fmt.Sprintf("LOST: %d events (%s)", total, describeLost(l.lost))
+	default:
+		result += fmt.Sprint("No such log type")
+	}
+	if l.capacity > 1 {
+		// vertices that fit more than one explorer say how full they are
+		result += fmt.Sprintf(" [%d/%d]", l.occupancy, l.capacity)
+	}
+	if l.team != nil {
+		result += fmt.Sprintf(" {%s}", l.team.name)
+	}
+	if l.stamp.Lamport > 0 {
+		result += " " + l.stamp.String()
+	}
+	return result
+}
+
+func MakeLogMsgBlueprint() LogMessage {
+	return LogMessage{timestamp: time.Now(), direction: None}
+}
+
+func MakeLogMsgWildLocatorSpawned(vertexId, x, y, wildId int, lifeTime time.Duration) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.wildId = wildId
+	msg.logType = LogMsgWildLocatorSpawned
+	msg.fromX = x
+	msg.fromY = y
+	msg.vertexId = vertexId
+	msg.lifeTime = lifeTime
+	return msg
+}
+
+func MakeLogMsgWildLocatorMoved(wildId, fromX, fromY, toX, toY int, direction LogDirection) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.wildId = wildId
+	msg.fromX = fromX
+	msg.fromY = fromY
+	msg.toX = toX
+	msg.toY = toY
+	msg.logType = LogMsgWildLocatorMoved
+	msg.direction = direction
+	return msg
+}
+
+func MakeLogMsgWildLocatorDied(wildId, x, y int) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.wildId = wildId
+	msg.logType = LogMsgWildLocatorDied
+	msg.fromX = x
+	msg.fromY = y
+	return msg
+}
+
+func MakeLogMsgExplorerSpawned(vertId, x, y, expId int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerSpawned
+	msg.fromX = x
+	msg.fromY = y
+	msg.expId = expId
+	msg.vertexId = vertId
+	return msg
+}
+
+func MakeLogMsgExplorerMoved(fromX, fromY, toX, toY, expId int, team *Team, direction LogDirection) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerMoved
+	msg.direction = direction
+	msg.fromX = fromX
+	msg.fromY = fromY
+	msg.toX = toX
+	msg.toY = toY
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgExplorerReceived(vertId, atX, atY, expId int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerReceived
+	msg.toX = atX
+	msg.toY = atY
+	msg.expId = expId
+	msg.vertexId = vertId
+	return msg
+}
+
+func MakeLogMsgExplorerLeft(vertId, fromX, fromY, expId int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerLeft
+	msg.fromX = fromX
+	msg.fromY = fromY
+	msg.vertexId = vertId
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgHazardSpawned(vertId, atX, atY int, lifeTime time.Duration) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.logType = LogMsgHazardSpawned
+	msg.toX = atX
+	msg.toY = atY
+	msg.vertexId = vertId
+	msg.lifeTime = lifeTime
+	return msg
+}
+
+func MakeLogMsgHazardDisappeared(vertId, atX, atY int) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.logType = LogMsgHazardDisappeared
+	msg.toX = atX
+	msg.toY = atY
+	msg.vertexId = vertId
+	return msg
+}
+
+func MakeLogMsgExplorerEnteredHazard(vertId, atX, atY, expId int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerEnteredHazard
+	msg.toX = atX
+	msg.toY = atY
+	msg.vertexId = vertId
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgExplorerDied(expId, atX, atY int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerDied
+	msg.toX = atX
+	msg.toY = atY
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgExplorerExhausted(expId, atX, atY int, team *Team) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerExhausted
+	msg.toX = atX
+	msg.toY = atY
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgExplorerProbed(fromX, fromY, toX, toY, expId int, team *Team, direction LogDirection) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.team = team
+	msg.logType = LogMsgExplorerProbed
+	msg.direction = direction
+	msg.fromX = fromX
+	msg.fromY = fromY
+	msg.toX = toX
+	msg.toY = toY
+	msg.expId = expId
+	return msg
+}
+
+func MakeLogMsgEventsLost(lost map[LogType]int) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.logType = LogMsgEventsLost
+	msg.vertexId = -1
+	msg.lost = lost
+	return msg
+}
+
+func MakeLogMsgChargingStation(vertId, atX, atY int) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.logType = LogMsgChargingStation
+	msg.toX = atX
+	msg.toY = atY
+	msg.vertexId = vertId
+	return msg
+}
+
+func MakeLogMsgOneWayEdge(vertId, fromX, fromY, toX, toY int, direction LogDirection) LogMessage {
+	msg := MakeLogMsgBlueprint()
+	msg.logType = LogMsgOneWayEdge
+	msg.fromX = fromX
+	msg.fromY = fromY
+	msg.toX = toX
+	msg.toY = toY
+	msg.direction = direction
+	msg.vertexId = vertId
+	return msg
+}
+
+func loggerRun(logChanel <-chan LogMessage, cameraChannel chan<- CameraMessage, tracker *worldTracker, logConfig LogConfig, clock Clock, events *eventHub) {
+	sinks := openLogSinks(logConfig)
+	defer func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}()
+
+	if logConfig.Order == OrderCausal {
+		logChanel = causalOrder(logChanel, logConfig.Holdback, clock)
+	}
+	for log := range logChanel {
+		if logConfig.wants(log.logType) {
+			line := log.String() + "\n"
+			for _, sink := range sinks {
+				sink.WriteLine(line)
+			}
+		}
+
+		tracker.Record(log)
+		events.Publish(log)
+
+		switch log.logType {
+		case LogMsgExplorerSpawned:
+			cameraChannel <- RecordSpawnExplorer(log.expId, log.team.colour, log.fromX, log.fromY)
+		case LogMsgExplorerMoved:
+			cameraChannel <- RecordMoveExplorer(log.expId, log.team.colour, log.fromX, log.fromY, log.toX, log.toY)
+		case LogMsgHazardSpawned:
+			cameraChannel <- RecordSpawnHazard(log.toX, log.toY)
+		case LogMsgHazardDisappeared:
+			cameraChannel <- RecordRemoveHazard(log.toX, log.toY)
+		case LogMsgExplorerEnteredHazard:
+			cameraChannel <- RecordRemoveHazard(log.toX, log.toY)
+		case LogMsgExplorerDied, LogMsgExplorerExhausted:
+			cameraChannel <- RecordRemoveExplorer(log.expId, log.toX, log.toY)
+		case LogMsgChargingStation:
+			cameraChannel <- RecordChargingStation(log.toX, log.toY)
+		case LogMsgOneWayEdge:
+			cameraChannel <- RecordOneWayEdge(log.fromX, log.fromY, log.toX, log.toY)
+		case LogMsgWildLocatorSpawned:
+			cameraChannel <- RecordSpawnWildLocator(log.fromX, log.fromY)
+		case LogMsgWildLocatorMoved:
+			cameraChannel <- RecordMoveWildLocator(log.fromX, log.fromY, log.toX, log.toY)
+		case LogMsgWildLocatorDied:
+			cameraChannel <- RecordRemoveWildLocator(log.fromX, log.fromY)
+		}
+
+	}
+
+	close(cameraChannel)
+}
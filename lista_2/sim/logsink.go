@@ -0,0 +1,267 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LogConfig says where loggerRun writes the log and which events it writes
+type LogConfig struct {
+	// Sinks are file paths or stdout or stderr, none of them means no log
+	Sinks []string
+	// MaxSize is the size in bytes a log file grows to before it is rotated,
+	// 0 turns rotation off
+	MaxSize int64
+	// Keep is how many rotated files are kept next to the current one
+	Keep int
+	// Events are the event types written to the sinks, nil means all of them,
+	// markers of lost events are always written
+	Events map[LogType]bool
+	// Buffer is how many events wait for the logger before Overflow kicks in
+	Buffer   int
+	Overflow OverflowPolicy
+	// Order is the order the events are written in, a causal order holds
+	// the events back for up to Holdback
+	Order    LogOrder
+	Holdback time.Duration
+}
+
+var logTypeNames = map[string]LogType{
+	"explorerSpawned":       LogMsgExplorerSpawned,
+	"explorerMoved":         LogMsgExplorerMoved,
+	"explorerReceived":      LogMsgExplorerReceived,
+	"explorerLeft":          LogMsgExplorerLeft,
+	"explorerDied":          LogMsgExplorerDied,
+	"explorerEnteredHazard": LogMsgExplorerEnteredHazard,
+	"hazardSpawned":         LogMsgHazardSpawned,
+	"hazardDisappeared":     LogMsgHazardDisappeared,
+	"wildLocatorSpawned":    LogMsgWildLocatorSpawned,
+	"wildLocatorMoved":      LogMsgWildLocatorMoved,
+	"wildLocatorDied":       LogMsgWildLocatorDied,
+	"explorerExhausted":     LogMsgExplorerExhausted,
+	"chargingStation":       LogMsgChargingStation,
+	"explorerProbed":        LogMsgExplorerProbed,
+	"oneWayEdge":            LogMsgOneWayEdge,
+}
+
+// ParseLogConfig reads the comma separated lists of sinks and event names
+// given on the command line
+func ParseLogConfig(sinks, events string, maxSize int64, keep int, buffer int, overflow string, order string, holdback time.Duration) (LogConfig, error) {
+	config := LogConfig{MaxSize: maxSize, Keep: keep, Buffer: buffer, Holdback: holdback}
+	if maxSize < 0 || keep < 0 {
+		return config, fmt.Errorf("the log size and the number of kept logs can't be negative")
+	}
+	if buffer < 1 {
+		return config, fmt.Errorf("the log buffer has to hold at least one event")
+	}
+	policy, err := parseOverflowPolicy(overflow)
+	if err != nil {
+		return config, err
+	}
+	config.Overflow = policy
+	config.Order, err = parseLogOrder(order)
+	if err != nil {
+		return config, err
+	}
+	if holdback < 0 {
+		return config, fmt.Errorf("the log holdback can't be negative")
+	}
+
+	for _, sink := range strings.Split(sinks, ",") {
+		sink = strings.TrimSpace(sink)
+		if sink == "" || sink == "none" {
+			continue
+		}
+		config.Sinks = append(config.Sinks, sink)
+	}
+
+	if events != "" && events != "all" {
+		config.Events = make(map[LogType]bool)
+		for _, name := range strings.Split(events, ",") {
+			logType, ok := logTypeNames[strings.TrimSpace(name)]
+			if !ok {
+				return config, fmt.Errorf("unknown log event %q", name)
+			}
+			config.Events[logType] = true
+		}
+	}
+	return config, nil
+}
+
+func (c LogConfig) wants(logType LogType) bool {
+	return c.Events == nil || c.Events[logType] || logType == LogMsgEventsLost
+}
+
+func logTypeName(logType LogType) string {
+	if logType == LogMsgEventsLost {
+		return "eventsLost"
+	}
+	for name, t := range logTypeNames {
+		if t == logType {
+			return name
+		}
+	}
+	return fmt.Sprint(int(logType))
+}
+
+// logSink is one place the log is written to. A sink that fails reports the
+// first error and only counts the ones after it, the simulation goes on.
+type logSink struct {
+	name   string
+	w      *bufio.Writer
+	file   *rotatingFile
+	errors int
+}
+
+func (s *logSink) fail(err error) {
+	if s.errors == 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: could not write the log to %s: %v, further errors are only counted\n", s.name, err)
+	}
+	s.errors += 1
+}
+
+func (s *logSink) WriteLine(line string) {
+	var err error
+	if s.file != nil {
+		err = s.file.WriteLine(line)
+	} else {
+		_, err = s.w.WriteString(line)
+	}
+	if err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *logSink) Close() {
+	var err error
+	if s.file != nil {
+		err = s.file.Close()
+	} else {
+		err = s.w.Flush()
+	}
+	if err != nil {
+		s.fail(err)
+	}
+	if s.errors > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: %d writes of the log to %s failed\n", s.errors, s.name)
+	}
+}
+
+func openLogSinks(config LogConfig) []*logSink {
+	sinks := make([]*logSink, 0, len(config.Sinks))
+	for _, name := range config.Sinks {
+		sink := &logSink{name: name}
+		switch name {
+		case "stdout":
+			sink.w = bufio.NewWriter(os.Stdout)
+		case "stderr":
+			sink.w = bufio.NewWriter(os.Stderr)
+		default:
+			file, err := openRotatingFile(name, config.MaxSize, config.Keep)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: could not open the log %s: %v\n", name, err)
+				continue
+			}
+			sink.file = file
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+// rotatingFile is a log file that is moved to path.1 when it grows over
+// maxSize, the older files move on to path.2 and so on up to path.keep
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	keep    int
+	f       *os.File
+	w       *bufio.Writer
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, keep: keep}
+	return r, r.open(os.O_TRUNC)
+}
+
+// open opens the file for writing, truncated or appended to
+func (r *rotatingFile) open(mode int) error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|mode, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.w = bufio.NewWriter(f)
+	r.size = info.Size()
+	return nil
+}
+
+// WriteLine writes the line, a line never gets split between two files
+func (r *rotatingFile) WriteLine(line string) error {
+	var rotateErr error
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		rotateErr = r.rotate()
+	}
+	if r.w == nil {
+		// the file could not be opened after the last rotation, try again
+		err := r.open(os.O_APPEND)
+		if err != nil {
+			return err
+		}
+	}
+	n, err := r.w.WriteString(line)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return err
+}
+
+func (r *rotatingFile) rotate() error {
+	err := r.Close()
+	r.f, r.w = nil, nil
+
+	for i := r.keep; i > 1; i-- {
+		renameErr := os.Rename(fmt.Sprintf("%s.%d", r.path, i-1), fmt.Sprintf("%s.%d", r.path, i))
+		if err == nil && renameErr != nil && !os.IsNotExist(renameErr) {
+			err = renameErr
+		}
+	}
+	mode := os.O_TRUNC
+	if r.keep > 0 {
+		renameErr := os.Rename(r.path, r.path+".1")
+		if renameErr != nil {
+			// keep writing to the file we have rather than losing it
+			mode = os.O_APPEND
+			if err == nil {
+				err = renameErr
+			}
+		}
+	}
+
+	openErr := r.open(mode)
+	if err == nil {
+		err = openErr
+	}
+	return err
+}
+
+func (r *rotatingFile) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if closeErr := r.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
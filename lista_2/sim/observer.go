@@ -0,0 +1,302 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// The observer protocol streams the camera events over tcp as newline
+// delimited json. The first line a client gets is
+//
+//	{"type":"hello","n":N,"m":M}
+//
+// followed by the events that rebuild the current board and then by every
+// new event, for example
+//
+//	{"type":"explorerMoved","x":1,"y":2,"toX":1,"toY":3,"expId":7,"colour":"red"}
+
+type observedEvent struct {
+	Type   string `json:"type"`
+	N      int    `json:"n,omitempty"`
+	M      int    `json:"m,omitempty"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	ToX    int    `json:"toX,omitempty"`
+	ToY    int    `json:"toY,omitempty"`
+	ExpId  int    `json:"expId,omitempty"`
+	Colour string `json:"colour,omitempty"`
+}
+
+var cameraMessageNames = map[CameraMessageType]string{
+	CamExplorerSpawned:    "explorerSpawned",
+	CamExplorerMoved:      "explorerMoved",
+	CamHazardSpawned:      "hazardSpawned",
+	CamHazardRemoved:      "hazardRemoved",
+	CamExplorerRemoved:    "explorerRemoved",
+	CamWildLocatorSpawned: "wildLocatorSpawned",
+	CamWildLocatorMoved:   "wildLocatorMoved",
+	CamWildLocatorRemoved: "wildLocatorRemoved",
+	CamChargingStation:    "chargingStation",
+	CamOneWayEdge:         "oneWayEdge",
+}
+
+func colourName(colour string) string {
+	for name, code := range teamColours {
+		if code == colour {
+			return name
+		}
+	}
+	return ""
+}
+
+func toObservedEvent(msg CameraMessage) observedEvent {
+	return observedEvent{
+		Type:   cameraMessageNames[msg.messageType],
+		X:      msg.x,
+		Y:      msg.y,
+		ToX:    msg.xHelper,
+		ToY:    msg.yHelper,
+		ExpId:  msg.expId,
+		Colour: colourName(msg.colour),
+	}
+}
+
+func fromObservedEvent(event observedEvent) (CameraMessage, bool) {
+	for msgType, name := range cameraMessageNames {
+		if name == event.Type {
+			msg := CameraMessage{messageType: msgType, x: event.X, y: event.Y, xHelper: event.ToX, yHelper: event.ToY, expId: event.ExpId, colour: teamColours[event.Colour]}
+			return msg, true
+		}
+	}
+	return CameraMessage{}, false
+}
+
+// ObserverServer sends the camera events to every connected client. It keeps
+// its own copy of the board, so a client that connects late first gets the
+// events that rebuild the board as it is now.
+type ObserverServer struct {
+	listener net.Listener
+	n        int
+	m        int
+	mu       sync.Mutex
+	board    Camera
+	clients  map[*observer]bool
+	closed   bool
+}
+
+type observer struct {
+	conn   net.Conn
+	events chan CameraMessage
+}
+
+func NewObserverServer(address string, n, m int) (*ObserverServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &ObserverServer{listener: listener, n: n, m: m, board: NewCamera(nil, n, m, io.Discard), clients: make(map[*observer]bool)}
+	go s.accept()
+	return s, nil
+}
+
+func (s *ObserverServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *ObserverServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		current := s.currentBoard()
+		o := &observer{conn: conn, events: make(chan CameraMessage, len(current)+observerBuffer)}
+		for _, msg := range current {
+			o.events <- msg
+		}
+		s.clients[o] = true
+		s.mu.Unlock()
+
+		go s.serve(o)
+	}
+}
+
+// currentBoard returns the events that rebuild the board as it is now
+func (s *ObserverServer) currentBoard() []CameraMessage {
+	current := s.board.oneWay.messages()
+	for y := 0; y < s.m; y++ {
+		for x := 0; x < s.n; x++ {
+			c := s.board.board[y][x]
+			if c.charging {
+				current = append(current, RecordChargingStation(x, y))
+			}
+			if c.hazard {
+				current = append(current, RecordSpawnHazard(x, y))
+			}
+			if c.hasWildLocator {
+				current = append(current, RecordSpawnWildLocator(x, y))
+			}
+			for _, e := range c.explorers {
+				current = append(current, RecordSpawnExplorer(e.expId, e.colour, x, y))
+			}
+		}
+	}
+	return current
+}
+
+func (s *ObserverServer) serve(o *observer) {
+	defer o.conn.Close()
+	w := bufio.NewWriter(o.conn)
+	encoder := json.NewEncoder(w)
+
+	err := encoder.Encode(observedEvent{Type: "hello", N: s.n, M: s.m})
+	for err == nil {
+		msg, ok := <-o.events
+		if !ok {
+			w.Flush()
+			return
+		}
+		err = encoder.Encode(toObservedEvent(msg))
+		if err == nil && len(o.events) == 0 {
+			// nothing else is waiting, so let the client see what we have
+			err = w.Flush()
+		}
+	}
+
+	s.mu.Lock()
+	s.drop(o)
+	s.mu.Unlock()
+}
+
+// drop forgets a client, it has to be called with the lock held
+func (s *ObserverServer) drop(o *observer) {
+	if s.clients[o] {
+		delete(s.clients, o)
+		close(o.events)
+	}
+}
+
+// Publish sends the event to every client, a client that can't keep up is
+// disconnected instead of slowing down the simulation
+func (s *ObserverServer) Publish(msg CameraMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.board.apply(msg)
+	s.board.ClearEdges()
+	for o := range s.clients {
+		select {
+		case o.events <- msg:
+		default:
+			fmt.Fprintln(os.Stderr, "WARNING: dropping an observer that can't keep up:", o.conn.RemoteAddr())
+			s.drop(o)
+		}
+	}
+}
+
+// Tee publishes every event read from in and passes it on to the returned
+// channel, which is closed when in is closed
+func (s *ObserverServer) Tee(in <-chan CameraMessage) <-chan CameraMessage {
+	out := make(chan CameraMessage, cameraBuffer)
+	go func() {
+		for msg := range in {
+			s.Publish(msg)
+			out <- msg
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Close stops accepting clients and ends the stream of every client once
+// it got all events published so far
+func (s *ObserverServer) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for o := range s.clients {
+		s.drop(o)
+	}
+}
+
+// startObserving puts an observer server between the logger and the camera
+// when an address is given
+func startObserving(address string, n, m int, cameraChannel <-chan CameraMessage, output io.Writer) (<-chan CameraMessage, func(), error) {
+	if address == "" {
+		return cameraChannel, func() {}, nil
+	}
+	server, err := NewObserverServer(address, n, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintln(output, "INFO: observers can connect to", server.Addr())
+	return server.Tee(cameraChannel), server.Close, nil
+}
+
+// Watch renders the board from the event stream of the observer server at
+// address, recording it to recordPath when one is given
+func Watch(address, recordPath string) error {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	var hello observedEvent
+	err = decoder.Decode(&hello)
+	if err != nil {
+		return err
+	}
+	if hello.Type != "hello" || hello.N < 1 || hello.M < 1 {
+		return fmt.Errorf("%s doesn't speak the observer protocol", address)
+	}
+
+	n, m := hello.N, hello.M
+	output, closeOutput, err := OpenOutput(recordPath, n, m)
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+	inside := func(x, y int) bool {
+		return x >= 0 && x < n && y >= 0 && y < m
+	}
+
+	cameraChannel := make(chan CameraMessage, cameraBuffer)
+	cameraDone := make(chan bool)
+	go func() {
+		camera := NewCamera(cameraChannel, n, m, output)
+		camera.Start()
+		cameraDone <- true
+	}()
+
+	for {
+		var event observedEvent
+		err = decoder.Decode(&event)
+		if err != nil {
+			break
+		}
+		msg, ok := fromObservedEvent(event)
+		if !ok || !inside(msg.x, msg.y) || !inside(msg.xHelper, msg.yHelper) {
+			fmt.Fprintln(os.Stderr, "WARNING: skipping a bad event:", event)
+			continue
+		}
+		cameraChannel <- msg
+	}
+	close(cameraChannel)
+	<-cameraDone
+	return nil
+}
@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestObservedEventRoundTrip(t *testing.T) {
+	for msgType, name := range cameraMessageNames {
+		msg := CameraMessage{messageType: msgType, x: 1, y: 2, xHelper: 3, yHelper: 4, expId: 5, colour: TERM_RED}
+		data, err := json.Marshal(toObservedEvent(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var event observedEvent
+		err = json.Unmarshal(data, &event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != name {
+			t.Errorf("%s went over the wire as %s", name, event.Type)
+		}
+		got, ok := fromObservedEvent(event)
+		if !ok || got != msg {
+			t.Errorf("%s came back as %v, want %v", name, got, msg)
+		}
+	}
+
+	if _, ok := fromObservedEvent(observedEvent{Type: "hello"}); ok {
+		t.Error("hello was taken for a camera event")
+	}
+}
+
+func TestObserverLateJoin(t *testing.T) {
+	server, err := NewObserverServer("127.0.0.1:0", 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	server.Publish(RecordSpawnExplorer(7, TERM_RED, 0, 0))
+	server.Publish(RecordMoveExplorer(7, TERM_RED, 0, 0, 1, 0))
+	server.Publish(RecordSpawnWildLocator(0, 1))
+	server.Publish(RecordSpawnHazard(2, 1))
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(wait))
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	next := func() observedEvent {
+		t.Helper()
+		var event observedEvent
+		err := decoder.Decode(&event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	hello := next()
+	if hello.Type != "hello" || hello.N != 3 || hello.M != 2 {
+		t.Fatalf("got %v instead of the hello", hello)
+	}
+	// the client is in, so this one comes live after the board
+	server.Publish(RecordRemoveHazard(2, 1))
+
+	want := []observedEvent{
+		{Type: "explorerSpawned", X: 1, Y: 0, ExpId: 7, Colour: "red"},
+		{Type: "wildLocatorSpawned", X: 0, Y: 1},
+		{Type: "hazardSpawned", X: 2, Y: 1},
+		{Type: "hazardRemoved", X: 2, Y: 1},
+	}
+	for _, w := range want {
+		got := next()
+		if got != w {
+			t.Errorf("got %v, want %v", got, w)
+		}
+	}
+}
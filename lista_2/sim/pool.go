@@ -0,0 +1,854 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// pool runs the lattice on a fixed number of workers instead of a routine per
+// vertex, explorer and wild locator. Every worker owns a rectangular block of
+// vertices together with the explorers and wild locators standing on them and
+// handles their ticks in a loop. Everything an explorer or a wild locator asks
+// of a vertex, even one of its own block, goes to the mailbox of the block
+// owning the vertex, so a vertex answers one message at a time just like its
+// routine would and the log reads the same.
+type pool struct {
+	lattice       *Lattice
+	env           *Env
+	explorerStats *ExplorerStats
+	maxExplorers  int
+	logQueue      *LogQueue
+	blocks        []*block
+	// the blocks form columns x rows, a vertex is in column blockX[x] and row
+	// blockY[y]
+	columns int
+	rows    int
+	blockX  []int
+	blockY  []int
+	// tick is how often the workers look at their block, the shortest tick
+	// of the vertices and the teams
+	tick time.Duration
+}
+
+// block is the part of the lattice one worker of the pool owns, the vertices
+// with x0 <= x < x1 and y0 <= y < y1
+type block struct {
+	pool         *pool
+	x0           int
+	y0           int
+	x1           int
+	y1           int
+	cells        []pooledVertex
+	explorers    []*pooledExplorer
+	wildLocators []*pooledWildLocator
+	mail         mailbox
+	ctrl         chan Message
+	rng          Rng
+	vertexDue    time.Time
+}
+
+// pooledVertex is what a block keeps next to a Vertex for the routine it replaces
+type pooledVertex struct {
+	// busy is set while the vertex waits for an explorer or a wild locator it
+	// let in, or for its wild locator to make room, a vertex routine doesn't
+	// listen to anyone then
+	busy bool
+	// hazardEnds is the active time the hazard timer fires at, zero when
+	// it's stopped
+	hazardEnds time.Time
+	wild       *pooledWildLocator
+	// evicting is the explorer waiting for the wild locator to make room
+	evicting *blockMessage
+}
+
+// blockMessage is a message in the mailbox of a block. Requests are for the
+// vertex at x,y, answers go back to the block from with the explorer or wild
+// locator that asked, only that block touches them.
+type blockMessage struct {
+	Message
+	from     *block
+	explorer *pooledExplorer
+	wild     *pooledWildLocator
+}
+
+// mailbox is an unbounded queue of messages, so blocks sending to each other
+// never wait on each other
+type mailbox struct {
+	mu    sync.Mutex
+	queue []blockMessage
+	ready chan struct{}
+}
+
+func (mb *mailbox) put(msg blockMessage) {
+	mb.mu.Lock()
+	mb.queue = append(mb.queue, msg)
+	mb.mu.Unlock()
+	select {
+	case mb.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the waiting messages and keeps spare for the next ones
+func (mb *mailbox) take(spare []blockMessage) []blockMessage {
+	mb.mu.Lock()
+	queue := mb.queue
+	mb.queue = spare[:0]
+	mb.mu.Unlock()
+	return queue
+}
+
+// pooledExplorer is an explorer run by a block. While it is moving it waits
+// for the answer of a neighbour and skips its ticks, like the routine blocked
+// on the answer.
+type pooledExplorer struct {
+	Explorer
+	due       time.Time
+	moving    bool
+	careful   bool
+	direction LogDirection
+	untried   []LogDirection
+	index     int
+}
+
+type pooledWildLocator struct {
+	WildLocator
+	due       time.Time
+	moving    bool
+	evicted   bool
+	direction LogDirection
+	untried   []LogDirection
+	index     int
+}
+
+func newPool(lattice *Lattice, workers int, explorerStats *ExplorerStats, maxExplorers int, logQueue *LogQueue) *pool {
+	env := lattice.env
+	n, m := lattice.n, lattice.m
+	columns, rows := poolShape(workers, n, m)
+	if columns*rows < workers {
+		fmt.Fprintf(os.Stderr, "WARNING: %d pool workers don't split the %dx%d lattice into blocks, using %d\n", workers, n, m, columns*rows)
+	}
+
+	p := &pool{
+		lattice:       lattice,
+		env:           env,
+		explorerStats: explorerStats,
+		maxExplorers:  maxExplorers,
+		logQueue:      logQueue,
+		columns:       columns,
+		rows:          rows,
+		blockX:        make([]int, n),
+		blockY:        make([]int, m),
+		tick:          tickTime,
+	}
+	for _, team := range env.teams {
+		if team.tickTime > 0 && team.tickTime < p.tick {
+			p.tick = team.tickTime
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		y0, y1 := regionRows(row, rows, m)
+		for column := 0; column < columns; column++ {
+			x0, x1 := regionRows(column, columns, n)
+			b := &block{
+				pool:  p,
+				x0:    x0,
+				y0:    y0,
+				x1:    x1,
+				y1:    y1,
+				cells: make([]pooledVertex, (x1-x0)*(y1-y0)),
+				ctrl:  make(chan Message),
+				rng:   newLockedRng(uint64(env.rng.Intn(math.MaxInt))),
+			}
+			b.mail.ready = make(chan struct{}, 1)
+			for y := y0; y < y1; y++ {
+				p.blockY[y] = row
+				for x := x0; x < x1; x++ {
+					p.blockX[x] = column
+					v := &lattice.vertices[y][x]
+					v.ctrl = b.ctrl
+					v.AttachLogger(logQueue)
+				}
+			}
+			p.blocks = append(p.blocks, b)
+		}
+	}
+	return p
+}
+
+// poolShape splits the workers into columns and rows of blocks that fit in
+// the lattice, with blocks as close to square as it gets. When the workers
+// can't be split that way it uses fewer of them.
+func poolShape(workers, n, m int) (int, int) {
+	for ; workers > 1; workers-- {
+		best, bestColumns := math.Inf(1), 0
+		for columns := 1; columns <= workers; columns++ {
+			rows := workers / columns
+			if columns*rows != workers || columns > n || rows > m {
+				continue
+			}
+			skew := math.Abs(math.Log(float64(n)/float64(columns)) - math.Log(float64(m)/float64(rows)))
+			if skew < best {
+				best, bestColumns = skew, columns
+			}
+		}
+		if bestColumns > 0 {
+			return bestColumns, workers / bestColumns
+		}
+	}
+	return 1, 1
+}
+
+func (p *pool) owner(x, y int) *block {
+	return p.blocks[p.blockY[y]*p.columns+p.blockX[x]]
+}
+
+// restore puts the world of a checkpoint on the lattice before the workers start
+func (p *pool) restore(snapshot Snapshot) {
+	restoreCounters(snapshot, p.env, p.explorerStats)
+	now := p.env.clock.Now()
+
+	for _, h := range snapshot.Hazards {
+		v, c := p.owner(h.X, h.Y).vertex(h.X, h.Y)
+		v.hazardous = true
+		c.hazardEnds = p.env.activeNow().Add(h.LifeLeft)
+		v.LogHazardSpawned(h.LifeLeft)
+	}
+
+	for _, e := range snapshot.Explorers {
+		b := p.owner(e.X, e.Y)
+		v, _ := b.vertex(e.X, e.Y)
+		b.startExplorer(v, e.Id, restoredTeam(p.env, e), e.Energy, now)
+	}
+
+	for _, w := range snapshot.WildLocators {
+		b := p.owner(w.X, w.Y)
+		v, c := b.vertex(w.X, w.Y)
+		b.startWildLocator(v, c, w.Id, w.LifeLeft, now)
+	}
+}
+
+// start runs a routine for every block
+func (p *pool) start(wg *sync.WaitGroup) {
+	for _, b := range p.blocks {
+		wg.Add(1)
+		go func(b *block) {
+			b.run()
+			wg.Done()
+		}(b)
+	}
+}
+
+func (b *block) vertex(x, y int) (*Vertex, *pooledVertex) {
+	return &b.pool.lattice.vertices[y][x], &b.cells[(y-b.y0)*(b.x1-b.x0)+x-b.x0]
+}
+
+func (b *block) run() {
+	env := b.pool.env
+	lattice := b.pool.lattice
+	for y := b.y0; y < b.y1; y++ {
+		for x := b.x0; x < b.x1; x++ {
+			v := &lattice.vertices[y][x]
+			if v.charging {
+				v.LogChargingStation()
+			}
+			for _, direction := range v.oneWayExits(lattice) {
+				v.LogOneWayEdge(direction)
+			}
+		}
+	}
+
+	b.vertexDue = env.clock.Now().Add(tickTime)
+	ticker := env.clock.NewTicker(b.pool.tick)
+	defer ticker.Stop()
+
+	var batch []blockMessage
+	for !env.shouldQuit() {
+		select {
+		case <-b.mail.ready:
+			batch = b.mail.take(batch)
+			for _, msg := range batch {
+				b.handle(msg)
+			}
+		case msg := <-b.ctrl:
+			b.control(msg)
+		case now := <-ticker.C():
+			b.step(now)
+		}
+	}
+}
+
+// step runs the ticks that are due. An explorer or a wild locator can only
+// take itself out of the block during its tick, so walking them backwards
+// visits each of them once.
+func (b *block) step(now time.Time) {
+	if !now.Before(b.vertexDue) {
+		b.vertexDue = nextTick(b.vertexDue, now, tickTime)
+		for y := b.y0; y < b.y1; y++ {
+			if b.pool.env.shouldQuit() {
+				// a large block takes a while, with a full log even longer
+				return
+			}
+			for x := b.x0; x < b.x1; x++ {
+				v, c := b.vertex(x, y)
+				if !c.busy {
+					b.vertexTick(v, c, now)
+				}
+			}
+		}
+	}
+
+	for i := len(b.explorers) - 1; i >= 0; i-- {
+		b.explorerTick(b.explorers[i], now)
+	}
+	for i := len(b.wildLocators) - 1; i >= 0; i-- {
+		b.wildLocatorTick(b.wildLocators[i], now)
+	}
+}
+
+// nextTick moves a due time one period on, ticks that were missed are dropped
+// like a ticker drops them
+func nextTick(due, now time.Time, period time.Duration) time.Time {
+	due = due.Add(period)
+	if due.Before(now) {
+		due = now.Add(period)
+	}
+	return due
+}
+
+func (b *block) handle(msg blockMessage) {
+	switch msg.msgType {
+	case MsgExplorerEnter:
+		b.explorerEnter(msg)
+	case MsgExplorerProbe:
+		b.answerProbe(msg)
+	case MsgExplorerReady:
+		b.explorerArrived(msg)
+	case MsgWildLocatorEnter:
+		b.wildLocatorEnter(msg)
+	case MsgWildLocatorReady:
+		b.wildLocatorArrived(msg)
+	default:
+		if msg.explorer != nil {
+			b.explorerAnswered(msg)
+		} else if msg.wild != nil {
+			b.wildLocatorAnswered(msg)
+		} else {
+			fmt.Fprintln(os.Stderr, "ERROR: unrecognized message type received by pool worker:", msg.Message)
+		}
+	}
+}
+
+// request sends a message to the vertex at x,y, the answer comes back to b
+func (b *block) request(x, y int, msg blockMessage) {
+	msg.x, msg.y = x, y
+	msg.from = b
+	b.pool.owner(x, y).mail.put(msg)
+}
+
+func (b *block) answer(request blockMessage, response Message) {
+	request.from.mail.put(blockMessage{Message: response, from: b, explorer: request.explorer, wild: request.wild})
+}
+
+// neighbours are the directions an explorer or a wild locator on (x,y) can
+// move in, in random order
+func (b *block) neighbours(x, y int) []LogDirection {
+	lattice := b.pool.lattice
+	v := &lattice.vertices[y][x]
+	directions := make([]LogDirection, 0, 4)
+	for _, direction := range []LogDirection{North, South, East, West} {
+		toX, toY := step(x, y, direction)
+		if toX < 0 || toX >= lattice.n || toY < 0 || toY >= lattice.m || v.blocked[direction] {
+			continue
+		}
+		directions = append(directions, direction)
+	}
+	for i := len(directions) - 1; i > 0; i-- {
+		j := b.rng.Intn(i + 1)
+		directions[i], directions[j] = directions[j], directions[i]
+	}
+	return directions
+}
+
+// vertexTick is the tick of a vertex routine that isn't busy
+func (b *block) vertexTick(v *Vertex, c *pooledVertex, now time.Time) {
+	env := b.pool.env
+	if len(v.explorers) == 0 && !v.hasWildLocator {
+		if !c.hazardEnds.IsZero() && !env.activeNow().Before(c.hazardEnds) {
+			c.hazardEnds = time.Time{}
+			v.hazardous = false
+			v.LogHazardDisappeared()
+		}
+		if env.isPaused() {
+			return
+		}
+
+		spawnExplorerRate := env.params.spawnExplorerRate.Load()
+		spawnHazardRate := env.params.spawnHazardRate.Load()
+		spawnWildLocatorRate := env.params.spawnWildLocatorRate.Load()
+
+		r := b.rng.Float64()
+		if !v.hazardous {
+			if r < spawnExplorerRate {
+				b.spawnExplorer(v, now)
+				return
+			}
+
+			r -= spawnExplorerRate
+			if r < spawnHazardRate {
+				v.hazardous = true
+				c.hazardEnds = env.activeNow().Add(hazardLifeTime)
+				v.LogHazardSpawned(hazardLifeTime)
+				return
+			}
+
+			r -= spawnHazardRate
+			if r < spawnWildLocatorRate {
+				b.spawnWildLocator(v, c, now)
+			}
+		} else if r < spawnWildLocatorRate {
+			// we can't spawn explorers or hazards if we already have a hazard
+			b.spawnWildLocator(v, c, now)
+		}
+	} else if len(v.explorers) > 0 {
+		if !env.isPaused() && len(v.explorers) < v.capacity && b.rng.Float64() < env.params.spawnExplorerRate.Load() {
+			b.spawnExplorer(v, now)
+		}
+	}
+}
+
+// control handles a message from the console, like handleCtrl of the vertex routine
+func (b *block) control(msg Message) {
+	env := b.pool.env
+	now := env.clock.Now()
+	v, c := b.vertex(msg.x, msg.y)
+	done := false
+	if !c.busy {
+		switch msg.msgType {
+		case MsgCtrlSpawnExplorer:
+			if len(v.explorers) < v.capacity && !v.hasWildLocator && !v.hazardous {
+				done = b.spawnExplorer(v, now)
+			}
+		case MsgCtrlSpawnHazard:
+			if len(v.explorers) == 0 && !v.hazardous {
+				v.hazardous = true
+				c.hazardEnds = env.activeNow().Add(hazardLifeTime)
+				v.LogHazardSpawned(hazardLifeTime)
+				done = true
+			}
+		case MsgCtrlSpawnWildLocator:
+			if len(v.explorers) == 0 && !v.hasWildLocator {
+				b.spawnWildLocator(v, c, now)
+				done = true
+			}
+		default:
+			fmt.Fprintln(os.Stderr, "ERROR: unrecognized control message received by pool worker:", msg)
+		}
+	}
+
+	response := Message{msgType: MsgCtrlRefused}
+	if done {
+		response.msgType = MsgCtrlDone
+	}
+	env.trySendMessage(msg.responseChannel, response)
+}
+
+func (b *block) spawnExplorer(v *Vertex, now time.Time) bool {
+	expId, ok := nextExplorerId(b.pool.explorerStats, b.pool.maxExplorers)
+	if !ok {
+		return false
+	}
+	b.startExplorer(v, expId, b.pool.env.pickTeam(), b.pool.env.energy.Capacity, now)
+	return true
+}
+
+// startExplorer places an explorer on the vertex, it has to be counted in explorerStats
+func (b *block) startExplorer(v *Vertex, expId int, team *Team, energy float64, now time.Time) {
+	e := &pooledExplorer{
+		Explorer: Explorer{id: expId, team: team, energy: energy, x: v.x, y: v.y, lattice: b.pool.lattice, env: b.pool.env},
+		due:      now.Add(team.tickTime),
+	}
+	v.addExplorer(expId)
+	team.spawned.Add(1)
+	v.LogExplorerSpawned(expId, team, energy)
+	e.logical = v.logical.fork("e", b.pool.env.slots)
+	e.AttachLogger(b.pool.logQueue)
+	b.takeExplorer(e)
+}
+
+func (b *block) takeExplorer(e *pooledExplorer) {
+	e.index = len(b.explorers)
+	b.explorers = append(b.explorers, e)
+}
+
+func (b *block) dropExplorer(e *pooledExplorer) {
+	last := len(b.explorers) - 1
+	b.explorers[e.index] = b.explorers[last]
+	b.explorers[e.index].index = e.index
+	b.explorers[last] = nil
+	b.explorers = b.explorers[:last]
+}
+
+// explorerTick is the tick of an explorer routine
+func (b *block) explorerTick(e *pooledExplorer, now time.Time) {
+	if e.moving || now.Before(e.due) {
+		return
+	}
+	e.due = nextTick(e.due, now, e.team.tickTime)
+
+	env := b.pool.env
+	if env.isPaused() {
+		return
+	}
+
+	e.charge()
+	if !e.spendEnergy(env.energy.TickCost) {
+		b.exhaustExplorer(e)
+		return
+	}
+
+	if b.rng.Float64() < e.team.moveRate.Load() {
+		e.moving = true
+		e.careful = env.sensing.Enabled
+		e.untried = b.neighbours(e.x, e.y)
+		b.explorerTryNext(e)
+	}
+}
+
+// explorerTryNext asks the next neighbour to let the explorer in, a careful
+// explorer probes it first. The explorer stays when no neighbour is left.
+func (b *block) explorerTryNext(e *pooledExplorer) {
+	if len(e.untried) == 0 {
+		e.moving = false
+		return
+	}
+	e.direction, e.untried = e.untried[0], e.untried[1:]
+
+	msgType := MsgExplorerEnter
+	if e.careful {
+		msgType = MsgExplorerProbe
+	}
+	x, y := step(e.x, e.y, e.direction)
+	b.request(x, y, blockMessage{Message: Message{msgType: msgType, expId: e.id, team: e.team, stamp: e.logical.Read()}, explorer: e})
+}
+
+// explorerAnswered handles the answer of a neighbour to a moving explorer,
+// like handleResponse and probe of the explorer routine
+func (b *block) explorerAnswered(msg blockMessage) {
+	e := msg.explorer
+	env := b.pool.env
+	switch msg.msgType {
+	case MsgVertexBusy:
+		b.explorerTryNext(e)
+	case MsgExplorerProbeResult:
+		e.logical.Receive(msg.stamp)
+		hazardous := msg.hazardous
+		if b.rng.Float64() < env.sensing.Noise {
+			hazardous = !hazardous
+		}
+		e.team.probes.Add(1)
+		e.LogExplorerProbed(e.direction, hazardous)
+		if hazardous {
+			b.explorerTryNext(e)
+			return
+		}
+		x, y := step(e.x, e.y, e.direction)
+		b.request(x, y, blockMessage{Message: Message{msgType: MsgExplorerEnter, expId: e.id, team: e.team, stamp: e.logical.Read()}, explorer: e})
+	case MsgExplorerEnterConfirm:
+		e.logical.Receive(msg.stamp)
+		b.explorerMoved(e)
+	case MsgExplorerEnterHazard:
+		e.logical.Receive(msg.stamp)
+		e.team.died.Add(1)
+		e.LogExplorerDied()
+		b.explorerGone(e)
+	case MsgExplorerEnterDeny:
+		// I guess we couldn't enter XD
+		e.logical.Receive(msg.stamp)
+		e.moving = false
+	default:
+		fmt.Fprintln(os.Stderr, "ERROR: this type of message should not be handled here:", msg.Message)
+	}
+}
+
+// explorerMoved hands the explorer over to the block of its new vertex and
+// leaves the old one
+func (b *block) explorerMoved(e *pooledExplorer) {
+	env := b.pool.env
+	e.spendEnergy(env.energy.MoveCost)
+	e.LogExplorerMoved(e.direction)
+	e.team.moves.Add(1)
+
+	v, _ := b.vertex(e.x, e.y)
+	expId, team := e.id, e.team
+	b.dropExplorer(e)
+	e.x, e.y = step(e.x, e.y, e.direction)
+	e.moving = false
+	stamp := e.logical.Read()
+	// the explorer belongs to the block of its new vertex from here
+	b.request(e.x, e.y, blockMessage{Message: Message{msgType: MsgExplorerReady, stamp: stamp}, explorer: e})
+
+	v.logical.Receive(stamp)
+	v.removeExplorer(expId)
+	v.LogExplorerLeft(expId, team)
+}
+
+// explorerArrived takes over an explorer the vertex let in
+func (b *block) explorerArrived(msg blockMessage) {
+	e := msg.explorer
+	v, c := b.vertex(e.x, e.y)
+	v.logical.Receive(msg.stamp)
+	c.busy = false
+	v.addExplorer(e.id)
+	v.LogExplorerReceived(e.id, e.team)
+	b.takeExplorer(e)
+
+	if e.energy <= 0 && b.pool.env.energy.Capacity > 0 {
+		// the move used up the last of the energy
+		b.exhaustExplorer(e)
+	}
+}
+
+func (b *block) exhaustExplorer(e *pooledExplorer) {
+	e.team.exhausted.Add(1)
+	e.LogExplorerExhausted()
+	b.explorerGone(e)
+}
+
+// explorerGone ends an explorer that died or ran out of energy
+func (b *block) explorerGone(e *pooledExplorer) {
+	v, _ := b.vertex(e.x, e.y)
+	v.logical.Receive(e.logical.Read())
+	v.removeExplorer(e.id)
+	v.LogExplorerLeft(e.id, e.team)
+	b.dropExplorer(e)
+	e.logical.end(b.pool.env.slots)
+
+	stats := b.pool.explorerStats
+	stats.mu.Lock()
+	stats.count -= 1
+	stats.mu.Unlock()
+}
+
+// explorerEnter is the vertex getting MsgExplorerEnter
+func (b *block) explorerEnter(msg blockMessage) {
+	v, c := b.vertex(msg.x, msg.y)
+	if c.busy {
+		b.answer(msg, Message{msgType: MsgVertexBusy})
+		return
+	}
+	v.logical.Receive(msg.stamp)
+
+	switch {
+	case len(v.explorers) == 0 && !v.hasWildLocator:
+		b.letExplorerIn(v, c, msg)
+	case len(v.explorers) > 0:
+		if len(v.explorers) < v.capacity {
+			b.letExplorerIn(v, c, msg)
+		} else {
+			// we are full
+			b.answer(msg, Message{msgType: MsgExplorerEnterDeny, stamp: v.logical.Read()})
+		}
+	default:
+		// the wild locator has to make room first
+		c.busy = true
+		c.evicting = &msg
+		w := c.wild
+		if !w.moving {
+			b.evictWildLocator(v, w)
+		}
+		// a wild locator that is already moving on its own makes room when
+		// it's done
+	}
+}
+
+// letExplorerIn is handleMsgExplorerEnter of the vertex routine, the vertex
+// waits for the explorer to log the move before it logs receiving it
+func (b *block) letExplorerIn(v *Vertex, c *pooledVertex, msg blockMessage) {
+	if !v.hazardous {
+		c.busy = true
+		b.answer(msg, Message{msgType: MsgExplorerEnterConfirm, stamp: v.logical.Read()})
+	} else {
+		b.answer(msg, Message{msgType: MsgExplorerEnterHazard, stamp: v.logical.Read()})
+		v.hazardous = false
+		v.LogMsgExplorerEnteredHazard(msg.expId, msg.team)
+	}
+}
+
+func (b *block) answerProbe(msg blockMessage) {
+	v, c := b.vertex(msg.x, msg.y)
+	if c.busy {
+		b.answer(msg, Message{msgType: MsgVertexBusy})
+		return
+	}
+	v.logical.Receive(msg.stamp)
+	b.answer(msg, Message{msgType: MsgExplorerProbeResult, hazardous: v.hazardous, stamp: v.logical.Read()})
+}
+
+func (b *block) spawnWildLocator(v *Vertex, c *pooledVertex, now time.Time) {
+	env := b.pool.env
+	b.startWildLocator(v, c, int(env.nextWildLocatorId.Add(env.wildLocatorIdStep)), WildLocatorLifeTime, now)
+}
+
+// startWildLocator places a wild locator with the given id on the vertex
+func (b *block) startWildLocator(v *Vertex, c *pooledVertex, id int, lifeTime time.Duration, now time.Time) {
+	w := &pooledWildLocator{
+		WildLocator: WildLocator{id: id, x: v.x, y: v.y, lattice: b.pool.lattice, env: b.pool.env, lifeTime: lifeTime, diesAt: b.pool.env.activeNow().Add(lifeTime)},
+		due:         now.Add(tickTime),
+	}
+	v.hasWildLocator = true
+	c.wild = w
+	v.LogWildLocatorSpawned(id, lifeTime)
+	w.logical = v.logical.fork("w", b.pool.env.slots)
+	w.AttachLogger(b.pool.logQueue)
+	b.takeWildLocator(w)
+}
+
+func (b *block) takeWildLocator(w *pooledWildLocator) {
+	w.index = len(b.wildLocators)
+	b.wildLocators = append(b.wildLocators, w)
+}
+
+func (b *block) dropWildLocator(w *pooledWildLocator) {
+	last := len(b.wildLocators) - 1
+	b.wildLocators[w.index] = b.wildLocators[last]
+	b.wildLocators[w.index].index = w.index
+	b.wildLocators[last] = nil
+	b.wildLocators = b.wildLocators[:last]
+}
+
+// wildLocatorTick is the timer and the ticker of a wild locator routine
+func (b *block) wildLocatorTick(w *pooledWildLocator, now time.Time) {
+	if w.moving {
+		return
+	}
+	if !b.pool.env.activeNow().Before(w.diesAt) {
+		// our time to live ended
+		v, c := b.vertex(w.x, w.y)
+		b.dropWildLocator(w)
+		b.wildLocatorLeft(v, c, w.logical.Read())
+		w.LogWildLocatorDied()
+		w.logical.end(b.pool.env.slots)
+		return
+	}
+	if now.Before(w.due) {
+		return
+	}
+	w.due = nextTick(w.due, now, tickTime)
+
+	env := b.pool.env
+	if env.roamingWildLocators && !env.isPaused() && b.rng.Float64() < env.params.wildLocatorMoveRate.Load() {
+		b.moveWildLocator(w, false)
+	}
+}
+
+// evictWildLocator asks the wild locator to make room for an explorer
+func (b *block) evictWildLocator(v *Vertex, w *pooledWildLocator) {
+	w.logical.Receive(v.logical.Read())
+	b.moveWildLocator(w, true)
+}
+
+// moveWildLocator starts the wild locator moving to a free neighbour, only
+// empty vertices that aren't busy take it in
+func (b *block) moveWildLocator(w *pooledWildLocator, evicted bool) {
+	w.moving = true
+	w.evicted = evicted
+	w.untried = b.neighbours(w.x, w.y)
+	b.wildLocatorTryNext(w)
+}
+
+func (b *block) wildLocatorTryNext(w *pooledWildLocator) {
+	if len(w.untried) == 0 {
+		b.wildLocatorStayed(w)
+		return
+	}
+	w.direction, w.untried = w.untried[0], w.untried[1:]
+	x, y := step(w.x, w.y, w.direction)
+	b.request(x, y, blockMessage{Message: Message{msgType: MsgWildLocatorEnter, wildId: w.id, stamp: w.logical.Read()}, wild: w})
+}
+
+func (b *block) wildLocatorAnswered(msg blockMessage) {
+	w := msg.wild
+	switch msg.msgType {
+	case MsgVertexBusy:
+		b.wildLocatorTryNext(w)
+	case MsgWildLocatorEnterConfirm:
+		w.logical.Receive(msg.stamp)
+		w.LogWildLocatorMoved(w.direction)
+
+		v, c := b.vertex(w.x, w.y)
+		b.dropWildLocator(w)
+		w.x, w.y = step(w.x, w.y, w.direction)
+		w.moving = false
+		stamp := w.logical.Read()
+		// the wild locator belongs to the block of its new vertex from here
+		b.request(w.x, w.y, blockMessage{Message: Message{msgType: MsgWildLocatorReady, stamp: stamp}, wild: w})
+		b.wildLocatorLeft(v, c, stamp)
+	default:
+		fmt.Fprintln(os.Stderr, "ERROR: the vertex didn't confirm entry")
+		b.wildLocatorTryNext(w)
+	}
+}
+
+// wildLocatorStayed ends a move no neighbour took the wild locator in for
+func (b *block) wildLocatorStayed(w *pooledWildLocator) {
+	w.moving = false
+	v, c := b.vertex(w.x, w.y)
+	if c.evicting == nil {
+		return
+	}
+	if !w.evicted {
+		// the vertex asked for room while the wild locator was roaming
+		b.evictWildLocator(v, w)
+		return
+	}
+
+	// there is nothing we can do ;-;
+	v.logical.Receive(w.logical.Read())
+	request := *c.evicting
+	c.evicting = nil
+	c.busy = false
+	b.answer(request, Message{msgType: MsgExplorerEnterDeny, stamp: v.logical.Read()})
+}
+
+// wildLocatorLeft empties the vertex of its wild locator, an explorer waiting
+// for the room gets in
+func (b *block) wildLocatorLeft(v *Vertex, c *pooledVertex, stamp Stamp) {
+	v.logical.Receive(stamp)
+	v.hasWildLocator = false
+	c.wild = nil
+	if c.evicting != nil {
+		request := *c.evicting
+		c.evicting = nil
+		c.busy = false
+		b.letExplorerIn(v, c, request)
+	}
+}
+
+// wildLocatorEnter is the vertex getting MsgWildLocatorEnter, it only
+// listens to wild locators while it is empty
+func (b *block) wildLocatorEnter(msg blockMessage) {
+	v, c := b.vertex(msg.x, msg.y)
+	if c.busy || len(v.explorers) > 0 || v.hasWildLocator {
+		b.answer(msg, Message{msgType: MsgVertexBusy})
+		return
+	}
+	v.logical.Receive(msg.stamp)
+	v.hasWildLocator = true
+	c.busy = true
+	b.answer(msg, Message{msgType: MsgWildLocatorEnterConfirm, stamp: v.logical.Read()})
+}
+
+// wildLocatorArrived takes over a wild locator the vertex let in
+func (b *block) wildLocatorArrived(msg blockMessage) {
+	w := msg.wild
+	v, c := b.vertex(w.x, w.y)
+	v.logical.Receive(msg.stamp)
+	c.busy = false
+	c.wild = w
+	b.takeWildLocator(w)
+}
@@ -0,0 +1,219 @@
+package sim
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// newPoolHarness is a harness whose lattice runs on the pool, with workers 0
+// it runs on the routines
+func newPoolHarness(t *testing.T, n, m, workers int) *harness {
+	h := newHarness(t, n, m)
+	if workers > 0 {
+		h.pool = newPool(h.lattice, workers, h.stats, 100, h.logQueue)
+	}
+	return h
+}
+
+// runLattice starts every vertex and what was placed on them
+func (h *harness) runLattice() {
+	h.t.Helper()
+	if h.pool != nil {
+		h.pool.start(&h.vertexWg)
+		h.started(len(h.pool.blocks))
+		return
+	}
+	for y := 0; y < h.lattice.m; y++ {
+		for x := 0; x < h.lattice.n; x++ {
+			h.runVertex(x, y)
+		}
+	}
+	h.started(h.lattice.n*h.lattice.m + h.placed)
+}
+
+// handshake is one of the handshakes of vertex_test.go with an explorer
+// doing the asking, so the pool can run it too
+type handshake struct {
+	name  string
+	n     int
+	m     int
+	setup func(h *harness)
+	// wait runs before the events are awaited
+	wait func(h *harness)
+	// events are the events of the handshake, the clock ticks until the
+	// first one comes
+	events []LogType
+	absent []LogType
+}
+
+var handshakes = []handshake{
+	{
+		name: "move", n: 2, m: 1,
+		setup: func(h *harness) {
+			h.placeExplorer(0, 0, 1)
+		},
+		events: []LogType{LogMsgExplorerMoved, LogMsgExplorerReceived, LogMsgExplorerLeft},
+	},
+	{
+		name: "hazard", n: 2, m: 1,
+		setup: func(h *harness) {
+			h.placeHazard(1, 0, 1000*tickTime)
+			h.placeExplorer(0, 0, 1)
+		},
+		events: []LogType{LogMsgExplorerEnteredHazard, LogMsgExplorerDied, LogMsgExplorerLeft},
+		absent: []LogType{LogMsgExplorerMoved},
+	},
+	{
+		name: "deny", n: 2, m: 1,
+		setup: func(h *harness) {
+			h.vertex(1, 0).explorers = []int{9}
+			h.placeExplorer(0, 0, 1)
+		},
+		wait:   tick(5),
+		absent: []LogType{LogMsgExplorerMoved, LogMsgExplorerReceived},
+	},
+	{
+		name: "eviction confirm", n: 3, m: 1,
+		setup: func(h *harness) {
+			h.placeWildLocator(1, 0, 1, 1000*tickTime)
+			h.placeExplorer(0, 0, 1)
+		},
+		events: []LogType{LogMsgWildLocatorMoved, LogMsgExplorerMoved, LogMsgExplorerReceived, LogMsgExplorerLeft},
+	},
+	{
+		name: "eviction deny", n: 2, m: 1,
+		setup: func(h *harness) {
+			// the locator can only go where the explorer is
+			h.placeWildLocator(1, 0, 1, 1000*tickTime)
+			h.placeExplorer(0, 0, 1)
+		},
+		wait:   tick(5),
+		absent: []LogType{LogMsgWildLocatorMoved, LogMsgExplorerMoved},
+	},
+	{
+		name: "locator timeout", n: 1, m: 1,
+		setup: func(h *harness) {
+			h.placeWildLocator(0, 0, 1, 3*tickTime)
+		},
+		wait: func(h *harness) {
+			h.clock.Advance(2 * tickTime)
+			h.refute(LogMsgWildLocatorDied)
+		},
+		events: []LogType{LogMsgWildLocatorDied},
+	},
+}
+
+// tick advances the clock a few ticks, giving the lattice time for each
+func tick(ticks int) func(h *harness) {
+	return func(h *harness) {
+		for i := 0; i < ticks; i++ {
+			h.clock.Advance(tickTime)
+			h.settle()
+		}
+	}
+}
+
+// run returns what the events of the handshake say happened
+func (s handshake) run(t *testing.T, workers int) []string {
+	h := newPoolHarness(t, s.n, s.m, workers)
+	s.setup(h)
+	h.runLattice()
+	if s.wait != nil {
+		s.wait(h)
+	}
+
+	var got []string
+	for i, logType := range s.events {
+		var log LogMessage
+		if i == 0 {
+			log = h.tickUntil(logType)
+		} else {
+			log = h.await(logType)
+		}
+		got = append(got, fmt.Sprintf("%d at vertex %d, explorer %d, wild locator %d, from %d,%d to %d,%d",
+			log.logType, log.vertexId, log.expId, log.wildId, log.fromX, log.fromY, log.toX, log.toY))
+	}
+	for _, logType := range s.absent {
+		h.refute(logType)
+	}
+	return got
+}
+
+func TestPoolHandshakes(t *testing.T) {
+	for _, s := range handshakes {
+		t.Run(s.name, func(t *testing.T) {
+			want := s.run(t, 0)
+			// one worker keeps the handshake in a block, one per vertex
+			// makes it cross the edges of the blocks
+			for _, workers := range []int{1, s.n * s.m} {
+				got := s.run(t, workers)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%d pool workers logged\n%v\nthe routines logged\n%v", workers, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolMovesAcrossBlocks(t *testing.T) {
+	h := newPoolHarness(t, 4, 1, 4)
+	h.placeExplorer(0, 0, 1)
+	h.runLattice()
+
+	// every move goes to another block
+	x := 0
+	for i := 0; i < 6; i++ {
+		moved := h.tickUntil(LogMsgExplorerMoved)
+		if moved.fromX != x || moved.expId != 1 {
+			t.Fatalf("explorer moved from %d, it is on %d: %v", moved.fromX, x, moved)
+		}
+		received := h.await(LogMsgExplorerReceived)
+		if received.vertexId != moved.toX || received.stamp.Lamport <= moved.stamp.Lamport {
+			t.Fatalf("vertex %d received the explorer moving to %d: %v", received.vertexId, moved.toX, received)
+		}
+		left := h.await(LogMsgExplorerLeft)
+		if left.vertexId != x {
+			t.Fatalf("explorer left vertex %d instead of %d", left.vertexId, x)
+		}
+		x = moved.toX
+	}
+}
+
+func TestPoolWildLocatorStamp(t *testing.T) {
+	h := newPoolHarness(t, 3, 1, 3)
+	h.placeWildLocator(1, 0, 1, 1000*tickTime)
+	h.placeExplorer(0, 0, 1)
+	h.runLattice()
+
+	moved := h.tickUntil(LogMsgWildLocatorMoved)
+	h.await(LogMsgExplorerReceived)
+	h.stop()
+	// the vertex the locator went to knows about the move
+	v := &h.lattice.vertices[0][moved.toX]
+	if v.logical.Read().Lamport < moved.stamp.Lamport {
+		t.Errorf("vertex %d is at %d, the move into it at %d", moved.toX, v.logical.Read().Lamport, moved.stamp.Lamport)
+	}
+}
+
+func TestPoolShape(t *testing.T) {
+	tests := []struct {
+		workers, n, m, columns, rows int
+	}{
+		{1, 10, 10, 1, 1},
+		{4, 10, 10, 2, 2},
+		{2, 10, 1, 2, 1},
+		{2, 1, 10, 1, 2},
+		{6, 30, 10, 3, 2},
+		// five blocks don't fit, four do
+		{5, 2, 2, 2, 2},
+		{3, 1, 1, 1, 1},
+		{7, 20, 20, 1, 7},
+	}
+	for _, test := range tests {
+		columns, rows := poolShape(test.workers, test.n, test.m)
+		if columns != test.columns || rows != test.rows {
+			t.Errorf("poolShape(%d, %d, %d) = %dx%d, want %dx%d", test.workers, test.n, test.m, columns, rows, test.columns, test.rows)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package sim is the explorer lattice simulation. The command line program is
+// a thin wrapper around it, other programs can run it with New and Run and
+// follow it with Subscribe and Snapshot.
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// TODO: Make error messages more meaningful
+// TODO: Split constants to correct files
+const (
+	tickTime             = 50 * time.Millisecond
+	spawnExplorerRate    = 0.05
+	moveExplorerRate     = 0.10
+	spawnHazardRate      = 0.05
+	hazardLifeTime       = 10 * tickTime
+	spawnWildLocatorRate = 0.05
+	WildLocatorLifeTime  = 10 * tickTime
+	moveWildLocatorRate  = 0.10
+	DefaultLogBuffer     = 100
+	DefaultLogHoldback   = 200 * time.Millisecond
+	minHoldbackTick      = time.Millisecond
+	runTime              = 5 * time.Second
+	cameraTick           = 100 * time.Millisecond
+	cameraBuffer         = 100
+	connectTimeout       = 10 * time.Second
+	proxyTimeout         = time.Second
+	observerBuffer       = 1000
+	subscriberBuffer     = 1000
+)
+
+// ExplorerStats counts the explorers of this process and hands out their ids,
+// ids go from firstId up to 99 in steps of idStep
+type ExplorerStats struct {
+	count   int
+	nextId  int
+	firstId int
+	idStep  int
+	mu      sync.Mutex
+}
+
+// newEnv builds the environment of a run from the config
+func newEnv(config Config, seed uint64, clocks ClockMode) *Env {
+	env := NewEnv(seed, NewTeams(config.Teams))
+	env.energy = config.Energy
+	env.sensing = config.Sensing
+	env.roamingWildLocators = config.WildLocators.Roaming
+	env.clocks = clocks
+	if config.WildLocators.MoveRate > 0 {
+		env.params.wildLocatorMoveRate.Store(config.WildLocators.MoveRate)
+	}
+	return env
+}
+
+// Options are the settings of a run. The command line fills them from its
+// flags, a program embedding the simulation fills them itself.
+type Options struct {
+	// N is the width and M the height of the lattice
+	N int
+	M int
+	// Seed of the random number generator, 0 picks one from the clock
+	Seed uint64
+	// Config holds the teams, energy, charging stations and capacities, it
+	// comes from DefaultConfig or ReadConfig
+	Config Config
+	// ConfigPath is where the workers of a distributed run read Config from
+	ConfigPath string
+	// Log says where the log goes, the zero value writes no log
+	Log LogConfig
+	// RunTime is how long Run runs unless its context ends first, 0 is the
+	// default of the command line
+	RunTime time.Duration
+	// Output gets the camera and the progress messages, nil runs without them
+	Output io.Writer
+	// Resume starts the run from a checkpoint
+	Resume *Snapshot
+	// CheckpointPath gets a checkpoint at the end of the run and on SIGUSR1
+	CheckpointPath string
+	// ConsoleAt is stdin or a unix socket path for console commands
+	ConsoleAt string
+	// ObserveAt is the tcp address remote observers connect to
+	ObserveAt string
+	// Clocks are the logical clocks that stamp the events, Lamport by default
+	Clocks ClockMode
+	// Pool runs the vertices on this many workers, each owning a block of
+	// the lattice, instead of a routine per vertex, explorer and wild
+	// locator. 0 runs the routines.
+	Pool int
+
+	// Workers, Network, WorkerIndex and WorkerDir set up a distributed run,
+	// see RunCoordinator and RunWorker
+	Workers     int
+	Network     string
+	WorkerIndex int
+	WorkerDir   string
+	// WorkerCommand starts a worker of a distributed run, the flags of the
+	// lista_2 command line are appended to it. Empty runs this executable
+	// again, which then has to be the lista_2 binary.
+	WorkerCommand []string
+}
+
+// withDefaults fills the options left at their zero value
+func (o Options) withDefaults() Options {
+	if o.Seed == 0 {
+		o.Seed = uint64(time.Now().UnixNano())
+	}
+	if o.RunTime == 0 {
+		o.RunTime = runTime
+	}
+	if o.Log.Buffer == 0 {
+		o.Log.Buffer = DefaultLogBuffer
+	}
+	if o.Log.Holdback == 0 {
+		o.Log.Holdback = DefaultLogHoldback
+	}
+	return o
+}
+
+// Simulation is one run of the lattice
+type Simulation struct {
+	opts          Options
+	env           *Env
+	lattice       Lattice
+	maxExplorers  int
+	explorerStats ExplorerStats
+	tracker       *worldTracker
+	events        *eventHub
+	started       atomic.Bool
+}
+
+// New builds the lattice of a run, nothing moves until Run is called
+func New(opts Options) (*Simulation, error) {
+	opts = opts.withDefaults()
+	if opts.Resume != nil {
+		opts.N, opts.M = opts.Resume.N, opts.Resume.M
+	}
+	if opts.N < 1 || opts.M < 1 {
+		return nil, errors.New("lattice dimensions must be positive")
+	}
+	if len(opts.Config.Teams) == 0 {
+		return nil, errors.New("at least one team is needed, start from DefaultConfig")
+	}
+	if opts.Log.Order == OrderCausal && opts.Clocks == ClocksNone {
+		return nil, errors.New("a causal log order needs clocks")
+	}
+
+	s := &Simulation{
+		opts:          opts,
+		env:           newEnv(opts.Config, opts.Seed, opts.Clocks),
+		explorerStats: ExplorerStats{count: 0, nextId: 1, firstId: 1, idStep: 1},
+		tracker:       newWorldTracker(opts.N, opts.M),
+		events:        newEventHub(),
+	}
+	if opts.Pool > 0 {
+		s.lattice = createVertices(opts.N, opts.M, s.env)
+	} else {
+		s.lattice = CreateLattice(opts.N, opts.M, s.env)
+	}
+	err := s.lattice.placeChargingStations(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	s.maxExplorers, err = s.lattice.setCapacities(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	err = s.lattice.setEdges(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Resume != nil {
+		s.maxExplorers = s.lattice.restoreLayout(*opts.Resume)
+	}
+	return s, nil
+}
+
+// Subscribe returns a channel that gets every event of the run from now on
+// and is closed when the run ends. The subscriber has to keep reading, one
+// that lets its channel fill up is dropped and its channel closed early.
+func (s *Simulation) Subscribe() <-chan Event {
+	return s.events.Subscribe()
+}
+
+// Snapshot returns the state of the world as far as the logger has seen it
+func (s *Simulation) Snapshot() Snapshot {
+	return takeSnapshot(s.tracker, &s.lattice, &s.explorerStats, s.env, s.env.clock.Now())
+}
+
+// Run runs the simulation until the run time is over, the context is done
+// or the console quits, and waits until every routine has stopped
+func (s *Simulation) Run(ctx context.Context) error {
+	if s.started.Swap(true) {
+		return errors.New("a simulation can only run once")
+	}
+
+	opts := s.opts
+	n, m := opts.N, opts.M
+	env := s.env
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+
+	logQueue := NewLogQueue(opts.Log.Buffer, opts.Log.Overflow)
+	loggerDone := make(chan bool)
+	cameraChanel := make(chan CameraMessage, cameraBuffer)
+	cameraDone := make(chan bool)
+
+	// the observer server is the only thing that can fail, so it starts
+	// before any routine does
+	cameraInput, stopObserving, err := startObserving(opts.ObserveAt, n, m, cameraChanel, output)
+	if err != nil {
+		s.events.Close()
+		return err
+	}
+
+	go func() {
+		loggerRun(logQueue.Messages(), cameraChanel, s.tracker, opts.Log, env.clock, s.events)
+		loggerDone <- true
+	}()
+
+	go func() {
+		if opts.Output != nil {
+			camera := NewCamera(cameraInput, n, m, opts.Output)
+			camera.Start()
+		} else {
+			for range cameraInput {
+			}
+		}
+		cameraDone <- true
+	}()
+
+	vertexWg := sync.WaitGroup{}
+	explorerWg := sync.WaitGroup{}
+	wildLocatorWg := sync.WaitGroup{}
+
+	var vertexPool *pool
+	if opts.Pool > 0 {
+		vertexPool = newPool(&s.lattice, opts.Pool, &s.explorerStats, s.maxExplorers, logQueue)
+		fmt.Fprintf(output, "INFO: running the lattice on %d pool workers in %dx%d blocks\n", len(vertexPool.blocks), vertexPool.columns, vertexPool.rows)
+	}
+
+	if opts.Resume != nil {
+		if vertexPool != nil {
+			vertexPool.restore(*opts.Resume)
+		} else {
+			restoreSnapshot(*opts.Resume, &s.lattice, &explorerWg, &s.explorerStats, &wildLocatorWg, logQueue)
+		}
+	}
+
+	if opts.CheckpointPath != "" {
+		if opts.Log.Overflow != OverflowBlock {
+			// the checkpoint is built from the log, so it misses whatever was dropped
+			fmt.Fprintln(os.Stderr, "WARNING: a checkpoint can be incomplete when log events are dropped, use -log-overflow block")
+		}
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGUSR1)
+		defer signal.Stop(signals)
+
+		go func() {
+			for range signals {
+				saveCheckpoint(output, opts.CheckpointPath, s.Snapshot())
+			}
+		}()
+	}
+
+	if vertexPool != nil {
+		vertexPool.start(&vertexWg)
+	} else {
+		vertexWg.Add(n * m)
+		for y := 0; y < m; y++ {
+			for x := 0; x < n; x++ {
+				go func(v Vertex) {
+					v.run(&explorerWg, &s.explorerStats, &wildLocatorWg, s.maxExplorers, logQueue, &s.lattice)
+					vertexWg.Done()
+				}(s.lattice.vertices[y][x])
+			}
+		}
+	}
+
+	console := NewConsole(&s.lattice, &s.explorerStats, s.tracker, opts.CheckpointPath)
+	stopConsole := startConsole(console, opts.ConsoleAt)
+
+	// the pauses of the console don't count towards the run time
+	runEnds := env.activeNow().Add(opts.RunTime)
+	runTimer := env.clock.NewTimer(opts.RunTime)
+	defer runTimer.Stop()
+	running := true
+	for running {
+		select {
+		case <-runTimer.C():
+			if left := env.timeLeft(runEnds); left > 0 {
+				runTimer.Reset(left)
+			} else {
+				running = false
+			}
+		case <-console.Quit():
+			running = false
+		case <-ctx.Done():
+			running = false
+		}
+	}
+	stopConsole()
+
+	fmt.Fprintln(output, "INFO: starting the exit sequence")
+
+	stoppedAt := env.clock.Now()
+	env.quit.Store(true)
+
+	vertexWg.Wait()
+	fmt.Fprintln(output, "INFO: all vertex routines finished")
+
+	explorerWg.Wait()
+	fmt.Fprintln(output, "INFO: all explorer routines finished")
+
+	wildLocatorWg.Wait()
+	fmt.Fprintln(output, "INFO: all wild locator routines finished")
+
+	logQueue.Close()
+
+	<-loggerDone
+	fmt.Fprintln(output, "INFO: logger routine finished")
+	logQueue.ReportDropped()
+	s.events.Close()
+
+	if opts.CheckpointPath != "" {
+		// the logger has seen every event of the run, so the tracked world is complete
+		saveCheckpoint(output, opts.CheckpointPath, takeSnapshot(s.tracker, &s.lattice, &s.explorerStats, env, stoppedAt))
+	}
+
+	<-cameraDone
+	stopObserving()
+	fmt.Fprintln(output, "INFO: camera routine finished")
+
+	for _, team := range env.teams {
+		fmt.Fprintln(output, "INFO: team", team)
+	}
+	return nil
+}
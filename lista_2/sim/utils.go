@@ -0,0 +1,91 @@
+package sim
+
+import (
+	"time"
+)
+
+type MessageType int
+
+type Message struct {
+	msgType         MessageType
+	expId           int
+	team            *Team
+	responseChannel chan Message
+	// hazardous is the state of the vertex in answers to probes
+	hazardous bool
+	// wildLocatorChannel is where a wild locator entering a vertex listens for eviction requests
+	wildLocatorChannel chan Message
+	// the state that travels with an explorer or a wild locator that enters a
+	// vertex, only needed when the vertex is in the region of another worker
+	energy   float64
+	wildId   int
+	lifeLeft time.Duration
+	// stamp is the logical clock of the sender
+	stamp Stamp
+	// x and y are the vertex a control message is for, a pool worker gets
+	// the control messages of its whole block on one channel
+	x int
+	y int
+}
+
+const (
+	MsgExplorerEnter MessageType = iota
+	MsgExplorerEnterConfirm
+	MsgExplorerEnterDeny
+	MsgExplorerEnterHazard
+	MsgExplorerLeave
+	MsgExplorerReady
+	MsgWildLocatorDied
+	MsgWildLocatorEvict
+	MsgWildLocatorEnter
+	MsgWildLocatorEnterConfirm
+	MsgWildLocatorEvictConfirm
+	MsgWildLocatorEvictDeny
+	MsgWildLocatorLeave
+	MsgExplorerProbe
+	MsgExplorerProbeResult
+	MsgCtrlSpawnExplorer
+	MsgCtrlSpawnHazard
+	MsgCtrlSpawnWildLocator
+	MsgCtrlDone
+	MsgCtrlRefused
+	MsgWildLocatorEnterDeny
+	// MsgWildLocatorReady hands a wild locator that moved over to the pool
+	// worker of its new vertex
+	MsgWildLocatorReady
+	// MsgVertexBusy answers a vertex of a pool that isn't listening right
+	// now, like a vertex routine in the middle of a handshake
+	MsgVertexBusy
+	// MsgExplorerEnterCancel comes instead of MsgExplorerReady when the
+	// worker of the explorer gave up on the move
+	MsgExplorerEnterCancel
+)
+
+func (env *Env) trySendMessage(channel chan<- Message, message Message) bool {
+	for !env.shouldQuit() {
+		timer := env.clock.NewTimer(10 * time.Millisecond)
+		select {
+		case channel <- message:
+			timer.Stop()
+			return true
+		case <-timer.C():
+			// recheck quit variable
+		}
+	}
+	return false
+}
+
+func (env *Env) tryRecievMessage(channel <-chan Message) *Message {
+	for !env.shouldQuit() {
+		timer := env.clock.NewTimer(10 * time.Millisecond)
+		select {
+		case response := <-channel:
+			// we recieved a response so we can procced
+			timer.Stop()
+			return &response
+		case <-timer.C():
+			// rerun quit variable check
+		}
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Lattice holds every vertex, but only the rows from firstRow up to lastRow
+// are run by this process, in a distributed run the other rows belong to
+// other workers
+type Lattice struct {
+	vertices [][]Vertex
+	n        int
+	m        int
+	firstRow int
+	lastRow  int
+	env      *Env
+	// capacity is the default capacity of the vertices
+	capacity int
+}
+
+func (l *Lattice) owns(y int) bool {
+	return y >= l.firstRow && y < l.lastRow
+}
+
+type Vertex struct {
+	logger                    *VertexLogger
+	env                       *Env
+	id                        int
+	x                         int
+	y                         int
+	explorers                 []int
+	capacity                  int
+	hasWildLocator            bool
+	hazardous                 bool
+	charging                  bool
+	hazardLifeLeft            time.Duration
+	currentWildLocatorChannel chan Message
+	in                        chan Message
+	out                       chan Message
+	inWild                    chan Message
+	outWild                   chan Message
+	ctrl                      chan Message
+	// blocked are the directions nothing can leave the vertex in, the edges
+	// there are one way towards it
+	blocked map[LogDirection]bool
+	logical *LogicalClock
+}
+
+func (v Vertex) run(explorerWg *sync.WaitGroup, explorerStats *ExplorerStats, wildLocatorWg *sync.WaitGroup, maxExplorers int, logQueue *LogQueue, lattice *Lattice) {
+	v.AttachLogger(logQueue)
+	ticker := v.env.clock.NewTicker(tickTime)
+	hazardTimer := v.env.clock.NewTimer(hazardLifeTime)
+	hazardTimer.Stop()
+	// hazardEnds is the active time the hazard disappears at
+	var hazardEnds time.Time
+	startHazard := func(lifeTime time.Duration) {
+		hazardEnds = v.env.activeNow().Add(lifeTime)
+		hazardTimer.Reset(lifeTime)
+	}
+	if v.hazardous {
+		// the hazard was restored from a checkpoint
+		startHazard(v.hazardLifeLeft)
+	}
+	if v.charging {
+		v.LogChargingStation()
+	}
+	for _, direction := range v.oneWayExits(lattice) {
+		v.LogOneWayEdge(direction)
+	}
+
+	// control messages come from the console and can arrive in any state
+	handleCtrl := func(msg Message) {
+		done := false
+		switch msg.msgType {
+		case MsgCtrlSpawnExplorer:
+			if len(v.explorers) < v.capacity && !v.hasWildLocator && !v.hazardous {
+				done = spawnExplorer(explorerWg, lattice, explorerStats, maxExplorers, &v, logQueue)
+			}
+		case MsgCtrlSpawnHazard:
+			if len(v.explorers) == 0 && !v.hazardous {
+				v.hazardous = true
+				startHazard(hazardLifeTime)
+				v.LogHazardSpawned(hazardLifeTime)
+				done = true
+			}
+		case MsgCtrlSpawnWildLocator:
+			if len(v.explorers) == 0 && !v.hasWildLocator {
+				spawnWildLocator(wildLocatorWg, lattice, &v, WildLocatorLifeTime, logQueue)
+				done = true
+			}
+		default:
+			fmt.Fprintln(os.Stderr, "ERROR: unrecognized control message received by vertex:", msg)
+		}
+
+		response := Message{msgType: MsgCtrlRefused}
+		if done {
+			response.msgType = MsgCtrlDone
+		}
+		v.env.trySendMessage(msg.responseChannel, response)
+	}
+
+	for !v.env.shouldQuit() {
+
+		if len(v.explorers) == 0 && !v.hasWildLocator {
+			// we don't currently have an explorer or wild locator so we can either spawn one of them or accept one from a neighbor
+			select {
+			case msg := <-v.in:
+				v.logical.Receive(msg.stamp)
+				switch msg.msgType {
+				case MsgExplorerEnter:
+					v.handleMsgExplorerEnter(msg)
+				case MsgExplorerProbe:
+					v.answerProbe(msg)
+				default:
+					fmt.Fprintln(os.Stderr, "ERROR: We should only receive MsgExplorerEnter or MsgExplorerProbe here")
+				}
+			case msg := <-v.inWild:
+				v.logical.Receive(msg.stamp)
+				if msg.msgType == MsgWildLocatorEnter {
+					response := Message{msgType: MsgWildLocatorEnterConfirm, stamp: v.logical.Read()}
+					ok := v.env.trySendMessage(msg.responseChannel, response)
+					if ok {
+						v.hasWildLocator = true
+						v.currentWildLocatorChannel = msg.wildLocatorChannel
+					}
+				} else {
+					fmt.Fprintln(os.Stderr, "ERROR: We should only receive MsgWildLocatorEnter here")
+				}
+			case msg := <-v.ctrl:
+				handleCtrl(msg)
+			case <-ticker.C():
+				if v.env.isPaused() {
+					continue
+				}
+
+				spawnExplorerRate := v.env.params.spawnExplorerRate.Load()
+				spawnHazardRate := v.env.params.spawnHazardRate.Load()
+				spawnWildLocatorRate := v.env.params.spawnWildLocatorRate.Load()
+
+				r := v.env.rng.Float64()
+				if !v.hazardous {
+					if r < spawnExplorerRate {
+						spawnExplorer(explorerWg, lattice, explorerStats, maxExplorers, &v, logQueue)
+						continue
+					}
+
+					r -= spawnExplorerRate
+					if r < spawnHazardRate {
+						v.hazardous = true
+						startHazard(hazardLifeTime)
+						v.LogHazardSpawned(hazardLifeTime)
+						continue
+					}
+
+					r -= spawnHazardRate
+					if r < spawnWildLocatorRate {
+						spawnWildLocator(wildLocatorWg, lattice, &v, WildLocatorLifeTime, logQueue)
+						continue
+					}
+				} else {
+					// we can't spawn explorers or hazards if we already have a hazard
+					if r < spawnWildLocatorRate {
+						spawnWildLocator(wildLocatorWg, lattice, &v, WildLocatorLifeTime, logQueue)
+						continue
+					}
+				}
+			case <-hazardTimer.C():
+				if left := v.env.timeLeft(hazardEnds); left > 0 {
+					// the run was paused in between
+					hazardTimer.Reset(left)
+					continue
+				}
+				v.hazardous = false
+				v.LogHazardDisappeared()
+			}
+		} else if len(v.explorers) > 0 {
+			select {
+			case msg := <-v.out:
+				v.logical.Receive(msg.stamp)
+				if msg.msgType == MsgExplorerLeave {
+					v.removeExplorer(msg.expId)
+					v.LogExplorerLeft(msg.expId, msg.team)
+				} else {
+					fmt.Fprintln(os.Stderr, "ERROR: We should only receive MsgExplorerLeave here:", msg)
+				}
+			case msg := <-v.in:
+				v.logical.Receive(msg.stamp)
+				switch msg.msgType {
+				case MsgExplorerEnter:
+					if len(v.explorers) < v.capacity {
+						v.handleMsgExplorerEnter(msg)
+					} else {
+						// we are full
+						v.env.trySendMessage(msg.responseChannel, Message{msgType: MsgExplorerEnterDeny, stamp: v.logical.Read()})
+					}
+				case MsgExplorerProbe:
+					v.answerProbe(msg)
+				default:
+					fmt.Fprintln(os.Stderr, "ERROR: We should only receive MsgExplorerEnter or MsgExplorerProbe here:", msg)
+				}
+			case msg := <-v.ctrl:
+				handleCtrl(msg)
+			case <-ticker.C():
+				// this also ensures that thread don't hang after all explorers close
+				if !v.env.isPaused() && len(v.explorers) < v.capacity && v.env.rng.Float64() < v.env.params.spawnExplorerRate.Load() {
+					spawnExplorer(explorerWg, lattice, explorerStats, maxExplorers, &v, logQueue)
+				}
+			}
+
+		} else if v.hasWildLocator {
+			// we have a wild locator so we need to listen to its messages as well we need to be able to accept a incoming explorer
+			select {
+			case msg := <-v.outWild:
+				v.logical.Receive(msg.stamp)
+				if msg.msgType == MsgWildLocatorDied || msg.msgType == MsgWildLocatorLeave {
+					v.hasWildLocator = false
+					v.currentWildLocatorChannel = nil
+				} else {
+					fmt.Fprintln(os.Stderr, "ERROR: We should only recieve MsgWildLocatorDied or MsgWildLocatorLeave here:", msg)
+				}
+			case msg := <-v.in:
+				v.logical.Receive(msg.stamp)
+				if msg.msgType == MsgExplorerEnter {
+					evicted := v.tryEvictLocator(msg)
+
+					if evicted {
+						v.handleMsgExplorerEnter(msg)
+					} else {
+						v.env.trySendMessage(msg.responseChannel, Message{msgType: MsgExplorerEnterDeny, stamp: v.logical.Read()})
+					}
+				} else if msg.msgType == MsgExplorerProbe {
+					v.answerProbe(msg)
+				} else {
+					fmt.Fprintln(os.Stderr, "ERROR: We should only recieve MsgExplorerEnter or MsgExplorerProbe here:", msg)
+				}
+			case msg := <-v.ctrl:
+				handleCtrl(msg)
+			case <-ticker.C():
+				//this ensures we don't hang after all other threads close
+			}
+
+		}
+	}
+	ticker.Stop()
+}
+
+func (v *Vertex) tryEvictLocator(msg Message) bool {
+	request := Message{msgType: MsgWildLocatorEvict, responseChannel: v.outWild, stamp: v.logical.Read()}
+	send := v.env.trySendMessage(v.currentWildLocatorChannel, request)
+
+	if !send {
+		return false
+	}
+
+	respond := v.env.tryRecievMessage(v.outWild)
+	if respond == nil {
+		return false
+	}
+	v.logical.Receive(respond.stamp)
+
+	evicted := false
+
+	switch respond.msgType {
+	case MsgWildLocatorEvictConfirm:
+		v.hasWildLocator = false
+		v.currentWildLocatorChannel = nil
+		evicted = true
+	case MsgWildLocatorEvictDeny:
+		// there is nothing we can do ;-;
+	default:
+		fmt.Fprintln(os.Stderr, "ERROR: locator didn't confirm or deny eviction request:", respond)
+	}
+
+	return evicted
+}
+
+func (v *Vertex) addExplorer(expId int) {
+	v.explorers = append(v.explorers, expId)
+}
+
+func (v *Vertex) removeExplorer(expId int) {
+	for i, id := range v.explorers {
+		if id == expId {
+			v.explorers = append(v.explorers[:i], v.explorers[i+1:]...)
+			return
+		}
+	}
+	fmt.Fprintln(os.Stderr, "ERROR: explorer left a vertex it was not in:", expId, v.x, v.y)
+}
+
+// answerProbe tells a neighbouring explorer what is on this vertex
+func (v *Vertex) answerProbe(msg Message) {
+	response := Message{msgType: MsgExplorerProbeResult, hazardous: v.hazardous, stamp: v.logical.Read()}
+	v.env.trySendMessage(msg.responseChannel, response)
+}
+
+func (v *Vertex) handleMsgExplorerEnter(msg Message) {
+	if !v.hazardous {
+		// the explorer says when it has logged the move, so the vertex
+		// doesn't log receiving it before that
+		ready := make(chan Message)
+		response := Message{msgType: MsgExplorerEnterConfirm, responseChannel: ready, stamp: v.logical.Read()}
+		ok := v.env.trySendMessage(msg.responseChannel, response)
+		if ok {
+			if res := v.env.tryRecievMessage(ready); res != nil {
+				v.logical.Receive(res.stamp)
+				if res.msgType == MsgExplorerEnterCancel {
+					// the explorer didn't come after all
+					return
+				}
+			}
+			v.addExplorer(msg.expId)
+			v.LogExplorerReceived(msg.expId, msg.team)
+		}
+	} else {
+		response := Message{msgType: MsgExplorerEnterHazard, stamp: v.logical.Read()}
+		ok := v.env.trySendMessage(msg.responseChannel, response)
+		if ok {
+			v.hazardous = false
+			v.LogMsgExplorerEnteredHazard(msg.expId, msg.team)
+		}
+	}
+}
+
+// oneWayExits are the directions the vertex can send to but not get back from
+func (v *Vertex) oneWayExits(lattice *Lattice) []LogDirection {
+	var exits []LogDirection
+	for _, direction := range []LogDirection{North, South, East, West} {
+		x, y := step(v.x, v.y, direction)
+		if x < 0 || x >= lattice.n || y < 0 || y >= lattice.m || v.blocked[direction] {
+			continue
+		}
+		if lattice.vertices[y][x].blocked[opposite(direction)] {
+			exits = append(exits, direction)
+		}
+	}
+	return exits
+}
+
+// step returns the coordinates one step from (x,y) in the direction
+func step(x, y int, direction LogDirection) (int, int) {
+	switch direction {
+	case North:
+		return x, y - 1
+	case South:
+		return x, y + 1
+	case East:
+		return x + 1, y
+	case West:
+		return x - 1, y
+	default:
+		return x, y
+	}
+}
+
+func opposite(direction LogDirection) LogDirection {
+	switch direction {
+	case North:
+		return South
+	case South:
+		return North
+	case East:
+		return West
+	case West:
+		return East
+	default:
+		return None
+	}
+}
+
+// CreateLattice builds a lattice n vertices wide and m vertices tall, indexed as vertices[y][x]
+func CreateLattice(n, m int, env *Env) Lattice {
+	lattice := createVertices(n, m, env)
+	for y := 0; y < m; y++ {
+		for x := 0; x < n; x++ {
+			v := &lattice.vertices[y][x]
+			v.in = make(chan Message)
+			v.out = make(chan Message)
+			v.inWild = make(chan Message)
+			v.outWild = make(chan Message)
+			v.ctrl = make(chan Message)
+		}
+	}
+	return lattice
+}
+
+// createVertices builds the lattice without the channels of the vertex
+// routines, a pool runs the vertices without them
+func createVertices(n, m int, env *Env) Lattice {
+	vertices := make([][]Vertex, m)
+	for y := 0; y < m; y++ {
+		vertices[y] = make([]Vertex, n)
+		for x := 0; x < n; x++ {
+			id := y*n + x
+			vertices[y][x] = Vertex{
+				env:      env,
+				capacity: 1,
+				id:       id,
+				x:        x,
+				y:        y,
+				logical:  newLogicalClock(fmt.Sprintf("v%d", id), env.clocks),
+			}
+		}
+	}
+
+	return Lattice{vertices: vertices, n: n, m: m, firstRow: 0, lastRow: m, env: env, capacity: 1}
+}
@@ -0,0 +1,462 @@
+package sim
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// wait is how long the tests wait in real time for a routine to answer
+const wait = 2 * time.Second
+
+// harness runs single vertices, explorers and wild locators of a small
+// lattice on a fake clock. The test plays the part of the routines it
+// doesn't run by talking to their channels itself. With a pool the lattice
+// runs on the pool workers instead.
+type harness struct {
+	t             *testing.T
+	clock         *fakeClock
+	rng           *scriptedRng
+	env           *Env
+	team          *Team
+	lattice       *Lattice
+	stats         *ExplorerStats
+	logQueue      *LogQueue
+	logs          <-chan LogMessage
+	seen          []LogMessage
+	vertexWg      sync.WaitGroup
+	explorerWg    sync.WaitGroup
+	wildLocatorWg sync.WaitGroup
+	pool          *pool
+	// placed counts the explorer and wild locator routines placed
+	placed  int
+	stopped bool
+}
+
+func newHarness(t *testing.T, n, m int) *harness {
+	team := &Team{name: "test", tickTime: tickTime}
+	team.moveRate.Store(1)
+	clock := newFakeClock()
+	rng := &scriptedRng{fallback: 0.5}
+	env := NewEnv(1, []*Team{team})
+	env.clock = clock
+	env.rng = rng
+	// nothing spawns on its own, the tests place what they need
+	env.params.spawnExplorerRate.Store(0)
+	env.params.spawnHazardRate.Store(0)
+	env.params.spawnWildLocatorRate.Store(0)
+
+	lattice := CreateLattice(n, m, env)
+	logQueue := NewLogQueue(1000, OverflowBlock)
+	h := &harness{
+		t:        t,
+		clock:    clock,
+		rng:      rng,
+		env:      env,
+		team:     team,
+		lattice:  &lattice,
+		stats:    &ExplorerStats{idStep: 1},
+		logQueue: logQueue,
+		logs:     logQueue.Messages(),
+	}
+	t.Cleanup(h.stop)
+	return h
+}
+
+func (h *harness) vertex(x, y int) *Vertex {
+	v := &h.lattice.vertices[y][x]
+	if v.logger == nil {
+		v.AttachLogger(h.logQueue)
+	}
+	return v
+}
+
+func (h *harness) runVertex(x, y int) {
+	v := h.vertex(x, y)
+	h.vertexWg.Add(1)
+	go func() {
+		v.run(&h.explorerWg, h.stats, &h.wildLocatorWg, 100, h.logQueue, h.lattice)
+		h.vertexWg.Done()
+	}()
+}
+
+// placeExplorer starts an explorer on a vertex that doesn't run yet
+func (h *harness) placeExplorer(x, y, id int) {
+	h.stats.count++
+	if h.pool != nil {
+		b := h.pool.owner(x, y)
+		v, _ := b.vertex(x, y)
+		b.startExplorer(v, id, h.team, 0, h.clock.Now())
+		return
+	}
+	h.placed++
+	startExplorer(&h.explorerWg, h.lattice, h.stats, h.vertex(x, y), id, h.team, 0, h.logQueue)
+}
+
+// placeWildLocator starts a wild locator on a vertex that doesn't run yet
+func (h *harness) placeWildLocator(x, y, id int, lifeTime time.Duration) {
+	if h.pool != nil {
+		b := h.pool.owner(x, y)
+		v, c := b.vertex(x, y)
+		b.startWildLocator(v, c, id, lifeTime, h.clock.Now())
+		return
+	}
+	h.placed++
+	startWildLocator(&h.wildLocatorWg, h.lattice, h.vertex(x, y), id, lifeTime, h.logQueue)
+}
+
+// placeHazard puts a hazard on a vertex that doesn't run yet
+func (h *harness) placeHazard(x, y int, lifeTime time.Duration) {
+	v := h.vertex(x, y)
+	v.hazardous = true
+	if h.pool != nil {
+		_, c := h.pool.owner(x, y).vertex(x, y)
+		c.hazardEnds = h.env.activeNow().Add(lifeTime)
+		return
+	}
+	v.hazardLifeLeft = lifeTime
+}
+
+// started waits until the routines made their tickers, a tick before that
+// would be missed
+func (h *harness) started(tickers int) {
+	h.t.Helper()
+	deadline := time.Now().Add(wait)
+	for h.clock.running() < tickers {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("only %d of %d routines started", h.clock.running(), tickers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// await returns the next event of the type, the events passed over on the
+// way are kept for later
+func (h *harness) await(logType LogType) LogMessage {
+	h.t.Helper()
+	return h.awaitTicking(logType, false)
+}
+
+// tickUntil ticks the clock until an event of the type comes, an explorer
+// only moves when its neighbour is listening at the moment of the tick
+func (h *harness) tickUntil(logType LogType) LogMessage {
+	h.t.Helper()
+	return h.awaitTicking(logType, true)
+}
+
+func (h *harness) awaitTicking(logType LogType, ticking bool) LogMessage {
+	h.t.Helper()
+	for i, log := range h.seen {
+		if log.logType == logType {
+			h.seen = append(h.seen[:i], h.seen[i+1:]...)
+			return log
+		}
+	}
+
+	deadline := time.After(wait)
+	for {
+		if ticking {
+			h.clock.Advance(tickTime)
+		}
+		select {
+		case log := <-h.logs:
+			if log.logType == logType {
+				return log
+			}
+			h.seen = append(h.seen, log)
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			h.t.Fatalf("no event of type %d, got %v", logType, h.seen)
+		}
+	}
+}
+
+// settle keeps the events that come until the routines go quiet
+func (h *harness) settle() {
+	for {
+		select {
+		case log := <-h.logs:
+			h.seen = append(h.seen, log)
+			continue
+		case <-time.After(20 * time.Millisecond):
+		}
+		break
+	}
+}
+
+// refute fails when an event of the type was logged
+func (h *harness) refute(logType LogType) {
+	h.t.Helper()
+	h.settle()
+	for _, log := range h.seen {
+		if log.logType == logType {
+			h.t.Fatalf("unexpected event %v", log)
+		}
+	}
+}
+
+// send passes a message to a routine, failing when it doesn't listen
+func (h *harness) send(channel chan<- Message, msg Message) {
+	h.t.Helper()
+	select {
+	case channel <- msg:
+	case <-time.After(wait):
+		h.t.Fatalf("nobody took %v", msg)
+	}
+}
+
+// receive waits for a message from a routine
+func (h *harness) receive(channel <-chan Message) Message {
+	h.t.Helper()
+	select {
+	case msg := <-channel:
+		return msg
+	case <-time.After(wait):
+		h.t.Fatalf("no message came")
+	}
+	return Message{}
+}
+
+// enter knocks on a vertex as an explorer would, returning the answer
+func (h *harness) enter(x, y, expId int) (Message, chan Message) {
+	h.t.Helper()
+	reply := make(chan Message)
+	h.send(h.lattice.vertices[y][x].in, Message{msgType: MsgExplorerEnter, expId: expId, team: h.team, responseChannel: reply})
+	return h.receive(reply), reply
+}
+
+// done waits for the routines counted by wg, ticking the clock so the ones
+// waiting for a message notice the quit
+func (h *harness) done(wg *sync.WaitGroup) bool {
+	finished := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	deadline := time.After(wait)
+	for {
+		h.clock.Advance(10 * time.Millisecond)
+		select {
+		case <-finished:
+			return true
+		case <-deadline:
+			return false
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (h *harness) stop() {
+	if h.stopped {
+		return
+	}
+	h.stopped = true
+	h.env.quit.Store(true)
+	if !h.done(&h.vertexWg) || !h.done(&h.explorerWg) || !h.done(&h.wildLocatorWg) {
+		h.t.Error("the routines didn't stop after quit")
+	}
+	h.logQueue.Close()
+}
+
+func TestExplorerMoves(t *testing.T) {
+	h := newHarness(t, 2, 1)
+	h.placeExplorer(0, 0, 1)
+	h.runVertex(0, 0)
+	h.runVertex(1, 0)
+	h.started(3)
+
+	moved := h.tickUntil(LogMsgExplorerMoved)
+	if moved.direction != East || moved.fromX != 0 || moved.toX != 1 || moved.expId != 1 {
+		t.Errorf("explorer moved wrong: %v", moved)
+	}
+	received := h.await(LogMsgExplorerReceived)
+	if received.vertexId != 1 || received.expId != 1 || received.occupancy != 1 {
+		t.Errorf("wrong vertex received the explorer: %v", received)
+	}
+	left := h.await(LogMsgExplorerLeft)
+	if left.vertexId != 0 || left.expId != 1 || left.occupancy != 0 {
+		t.Errorf("explorer left the wrong vertex: %v", left)
+	}
+	if received.stamp.Lamport <= moved.stamp.Lamport {
+		t.Errorf("received at %d isn't after moved at %d", received.stamp.Lamport, moved.stamp.Lamport)
+	}
+}
+
+func TestVertexSpawnsExplorer(t *testing.T) {
+	h := newHarness(t, 1, 1)
+	h.env.params.spawnExplorerRate.Store(0.3)
+	// the first tick is under the rate, after that the fallback is over it
+	h.rng.floats = []float64{0.1}
+	h.runVertex(0, 0)
+	h.started(1)
+
+	spawned := h.tickUntil(LogMsgExplorerSpawned)
+	if spawned.vertexId != 0 || spawned.team != h.team {
+		t.Errorf("wrong explorer spawned: %v", spawned)
+	}
+	h.clock.Advance(tickTime)
+	h.refute(LogMsgExplorerMoved)
+}
+
+func TestExplorerMovesIntoHazard(t *testing.T) {
+	h := newHarness(t, 2, 1)
+	h.placeHazard(1, 0, 1000*tickTime)
+	h.placeExplorer(0, 0, 1)
+	h.runVertex(0, 0)
+	h.runVertex(1, 0)
+	h.started(3)
+
+	entered := h.tickUntil(LogMsgExplorerEnteredHazard)
+	if entered.vertexId != 1 || entered.expId != 1 {
+		t.Errorf("explorer entered the wrong hazard: %v", entered)
+	}
+	died := h.await(LogMsgExplorerDied)
+	if died.toX != 0 || died.expId != 1 {
+		t.Errorf("explorer died in the wrong place: %v", died)
+	}
+	left := h.await(LogMsgExplorerLeft)
+	if left.vertexId != 0 {
+		t.Errorf("dead explorer left the wrong vertex: %v", left)
+	}
+	if !h.done(&h.explorerWg) {
+		t.Fatal("the dead explorer still runs")
+	}
+
+	// the hazard took the explorer and is gone
+	answer, _ := h.enter(1, 0, 2)
+	if answer.msgType != MsgExplorerEnterConfirm {
+		t.Fatalf("vertex didn't let the next explorer in: %v", answer)
+	}
+	h.send(answer.responseChannel, Message{msgType: MsgExplorerReady})
+	h.await(LogMsgExplorerReceived)
+}
+
+func TestVertexDeniesWhenFull(t *testing.T) {
+	h := newHarness(t, 1, 1)
+	h.vertex(0, 0).explorers = []int{9}
+	h.runVertex(0, 0)
+	h.started(1)
+
+	answer, _ := h.enter(0, 0, 1)
+	if answer.msgType != MsgExplorerEnterDeny {
+		t.Fatalf("full vertex answered %v", answer)
+	}
+	h.refute(LogMsgExplorerReceived)
+}
+
+func TestEvictionConfirmed(t *testing.T) {
+	h := newHarness(t, 3, 1)
+	h.placeWildLocator(1, 0, 1, 1000*tickTime)
+	h.runVertex(1, 0)
+	h.runVertex(2, 0)
+	h.started(3)
+
+	// the explorer comes from the west, the locator makes room by going east
+	answer, _ := h.enter(1, 0, 1)
+	if answer.msgType != MsgExplorerEnterConfirm {
+		t.Fatalf("vertex didn't evict the wild locator: %v", answer)
+	}
+	h.send(answer.responseChannel, Message{msgType: MsgExplorerReady})
+
+	moved := h.await(LogMsgWildLocatorMoved)
+	if moved.direction != East || moved.toX != 2 || moved.wildId != 1 {
+		t.Errorf("wild locator moved wrong: %v", moved)
+	}
+	received := h.await(LogMsgExplorerReceived)
+	if received.vertexId != 1 || received.expId != 1 {
+		t.Errorf("wrong vertex received the explorer: %v", received)
+	}
+}
+
+func TestEvictionDenied(t *testing.T) {
+	h := newHarness(t, 2, 1)
+	h.placeWildLocator(1, 0, 1, 1000*tickTime)
+	h.runVertex(1, 0)
+	h.started(2)
+
+	// the only other vertex doesn't run, so the locator has nowhere to go
+	answer, _ := h.enter(1, 0, 1)
+	if answer.msgType != MsgExplorerEnterDeny {
+		t.Fatalf("vertex let the explorer in over the wild locator: %v", answer)
+	}
+	h.refute(LogMsgWildLocatorMoved)
+
+	// and it still holds the locator
+	answer, _ = h.enter(1, 0, 2)
+	if answer.msgType != MsgExplorerEnterDeny {
+		t.Fatalf("vertex forgot the wild locator: %v", answer)
+	}
+}
+
+func TestWildLocatorDiesOnTimeout(t *testing.T) {
+	h := newHarness(t, 1, 1)
+	h.placeWildLocator(0, 0, 1, 3*tickTime)
+	h.runVertex(0, 0)
+	h.started(2)
+
+	h.clock.Advance(2 * tickTime)
+	h.refute(LogMsgWildLocatorDied)
+	h.clock.Advance(tickTime)
+	died := h.await(LogMsgWildLocatorDied)
+	if died.wildId != 1 || died.fromX != 0 {
+		t.Errorf("wrong wild locator died: %v", died)
+	}
+	if !h.done(&h.wildLocatorWg) {
+		t.Fatal("the dead wild locator still runs")
+	}
+
+	// the vertex is free again
+	answer, _ := h.enter(0, 0, 1)
+	if answer.msgType != MsgExplorerEnterConfirm {
+		t.Fatalf("vertex still holds the dead wild locator: %v", answer)
+	}
+	h.send(answer.responseChannel, Message{msgType: MsgExplorerReady})
+	h.await(LogMsgExplorerReceived)
+}
+
+func TestQuitMidHandshake(t *testing.T) {
+	t.Run("vertex", func(t *testing.T) {
+		h := newHarness(t, 1, 1)
+		h.runVertex(0, 0)
+		h.started(1)
+
+		// the explorer gets the confirm but never says it is ready
+		answer, _ := h.enter(0, 0, 1)
+		if answer.msgType != MsgExplorerEnterConfirm {
+			t.Fatalf("vertex didn't let the explorer in: %v", answer)
+		}
+		h.env.quit.Store(true)
+		if !h.done(&h.vertexWg) {
+			t.Fatal("vertex waits for the explorer after quit")
+		}
+	})
+
+	t.Run("explorer", func(t *testing.T) {
+		h := newHarness(t, 2, 1)
+		h.placeExplorer(0, 0, 1)
+		h.runVertex(0, 0)
+		h.started(2)
+
+		// the neighbour takes the request and never answers
+		deadline := time.After(wait)
+	knock:
+		for {
+			h.clock.Advance(tickTime)
+			select {
+			case msg := <-h.lattice.vertices[0][1].in:
+				if msg.msgType != MsgExplorerEnter {
+					t.Fatalf("explorer sent %v", msg)
+				}
+				break knock
+			case <-time.After(10 * time.Millisecond):
+			case <-deadline:
+				t.Fatal("explorer never tried to move")
+			}
+		}
+		h.env.quit.Store(true)
+		if !h.done(&h.explorerWg) {
+			t.Fatal("explorer waits for the answer after quit")
+		}
+		h.refute(LogMsgExplorerMoved)
+	})
+}
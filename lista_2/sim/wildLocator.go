@@ -0,0 +1,224 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type WildLocator struct {
+	logger   *WildLocatorLogger
+	env      *Env
+	lattice  *Lattice
+	id       int
+	x        int
+	y        int
+	lifeTime time.Duration
+	diesAt   time.Time
+	self     chan Message
+	reply    chan Message
+	current  chan<- Message
+	north    chan<- Message
+	south    chan<- Message
+	east     chan<- Message
+	west     chan<- Message
+	logical  *LogicalClock
+}
+
+func spawnWildLocator(wg *sync.WaitGroup, lattice *Lattice, v *Vertex, lifeTime time.Duration, logQueue *LogQueue) {
+	startWildLocator(wg, lattice, v, int(lattice.env.nextWildLocatorId.Add(lattice.env.wildLocatorIdStep)), lifeTime, logQueue)
+}
+
+// startWildLocator places a wild locator with the given id on the vertex and runs it
+func startWildLocator(wg *sync.WaitGroup, lattice *Lattice, v *Vertex, id int, lifeTime time.Duration, logQueue *LogQueue) {
+	wildLocator := WildLocator{id: id, x: v.x, y: v.y, lattice: lattice, env: lattice.env, lifeTime: lifeTime, self: make(chan Message), reply: make(chan Message)}
+	v.hasWildLocator = true
+	v.currentWildLocatorChannel = wildLocator.self
+	v.LogWildLocatorSpawned(id, lifeTime)
+	wildLocator.logical = v.logical.fork("w", lattice.env.slots)
+	runWildLocator(wg, wildLocator, logQueue)
+}
+
+// runWildLocator runs a wild locator that already stands on its vertex
+func runWildLocator(wg *sync.WaitGroup, wildLocator WildLocator, logQueue *LogQueue) {
+	wg.Add(1)
+
+	go func() {
+		// setup wildLocator and run it
+		wildLocator.updateChannels()
+		wildLocator.AttachLogger(logQueue)
+		wildLocator.run()
+		wildLocator.logical.end(wildLocator.env.slots)
+
+		wg.Done()
+	}()
+}
+
+func (w *WildLocator) run() {
+	ticker := w.env.clock.NewTicker(tickTime)
+	defer ticker.Stop()
+
+	w.diesAt = w.env.activeNow().Add(w.lifeTime)
+	timer := w.env.clock.NewTimer(w.lifeTime)
+	defer timer.Stop()
+
+	alive := true
+
+	for !w.env.shouldQuit() && alive {
+		select {
+		case <-timer.C():
+			if left := w.env.timeLeft(w.diesAt); left > 0 {
+				// the run was paused, we live on for as long as it was
+				timer.Reset(left)
+			} else {
+				// our time to live ended
+				w.leaveVertex(w.current, MsgWildLocatorDied)
+				w.LogWildLocatorDied()
+				alive = false
+			}
+		case <-ticker.C():
+			if w.env.roamingWildLocators && !w.env.isPaused() && w.env.rng.Float64() < w.env.params.wildLocatorMoveRate.Load() {
+				w.roam()
+			}
+		case msg := <-w.self:
+			w.logical.Receive(msg.stamp)
+			// we got a message from vertex we are in handle it correctly
+			switch msg.msgType {
+			case MsgWildLocatorEvict:
+				moved := w.tryToMove()
+
+				if moved {
+					w.env.trySendMessage(w.current, Message{msgType: MsgWildLocatorEvictConfirm, stamp: w.logical.Read()})
+					w.updateChannels()
+				} else {
+					w.env.trySendMessage(w.current, Message{msgType: MsgWildLocatorEvictDeny, stamp: w.logical.Read()})
+				}
+			default:
+				fmt.Fprintln(os.Stderr, "ERROR: unrecognized message type received by wild locator:", msg)
+			}
+		}
+
+		if alive && !w.lattice.owns(w.y) {
+			// the worker running that region took the wild locator over
+			alive = false
+		}
+	}
+}
+
+// roam moves the wild locator to a random free neighbour on its own
+func (w *WildLocator) roam() {
+	previous := w.current
+	if !w.tryToMove() {
+		return
+	}
+	w.updateChannels()
+	w.leaveVertex(previous, MsgWildLocatorLeave)
+}
+
+// leaveVertex tells the vertex that we are no longer there. The vertex may be
+// busy asking us to make room for an explorer at the same time, then we
+// confirm the eviction instead, as we are already gone. Evictions asked by any
+// other vertex are denied until we are done.
+func (w *WildLocator) leaveVertex(vertex chan<- Message, msgType MessageType) {
+	for !w.env.shouldQuit() {
+		timer := w.env.clock.NewTimer(10 * time.Millisecond)
+		select {
+		case vertex <- Message{msgType: msgType, stamp: w.logical.Read()}:
+			timer.Stop()
+			return
+		case msg := <-w.self:
+			timer.Stop()
+			w.logical.Receive(msg.stamp)
+			if msg.msgType != MsgWildLocatorEvict {
+				fmt.Fprintln(os.Stderr, "ERROR: unrecognized message type received by wild locator:", msg)
+				continue
+			}
+			if msg.responseChannel == vertex {
+				w.env.trySendMessage(vertex, Message{msgType: MsgWildLocatorEvictConfirm, stamp: w.logical.Read()})
+				return
+			}
+			w.env.trySendMessage(msg.responseChannel, Message{msgType: MsgWildLocatorEvictDeny, stamp: w.logical.Read()})
+		case <-timer.C():
+			// recheck quit variable
+		}
+	}
+}
+
+func (w *WildLocator) tryToMove() bool {
+	msg := Message{msgType: MsgWildLocatorEnter, responseChannel: w.reply, wildLocatorChannel: w.self, wildId: w.id, lifeLeft: w.diesAt.Sub(w.env.activeNow()), stamp: w.logical.Read()}
+	var moved bool
+	select {
+	case w.north <- msg:
+		moved = w.handleResponse(North)
+	case w.south <- msg:
+		moved = w.handleResponse(South)
+	case w.east <- msg:
+		moved = w.handleResponse(East)
+	case w.west <- msg:
+		moved = w.handleResponse(West)
+	default:
+	}
+	return moved
+}
+
+func (w *WildLocator) handleResponse(direction LogDirection) bool {
+	res := w.env.tryRecievMessage(w.reply)
+
+	if res == nil {
+		return false
+	}
+	w.logical.Receive(res.stamp)
+
+	if res.msgType == MsgWildLocatorEnterDeny {
+		// the vertex is in a region we can't reach right now
+		return false
+	}
+	if res.msgType != MsgWildLocatorEnterConfirm {
+		fmt.Fprintln(os.Stderr, "ERROR: the vertex didn't confirm entry")
+		return false
+	}
+
+	w.LogWildLocatorMoved(direction)
+	switch direction {
+	case North:
+		w.y -= 1
+	case South:
+		w.y += 1
+	case West:
+		w.x -= 1
+	case East:
+		w.x += 1
+	}
+
+	return true
+}
+
+func (w *WildLocator) updateChannels() {
+	v := &w.lattice.vertices[w.y][w.x]
+	w.current = v.outWild
+
+	if w.y > 0 && !v.blocked[North] {
+		w.north = w.lattice.vertices[w.y-1][w.x].inWild
+	} else {
+		w.north = nil
+	}
+
+	if w.y < w.lattice.m-1 && !v.blocked[South] {
+		w.south = w.lattice.vertices[w.y+1][w.x].inWild
+	} else {
+		w.south = nil
+	}
+
+	if w.x > 0 && !v.blocked[West] {
+		w.west = w.lattice.vertices[w.y][w.x-1].inWild
+	} else {
+		w.west = nil
+	}
+
+	if w.x < w.lattice.n-1 && !v.blocked[East] {
+		w.east = w.lattice.vertices[w.y][w.x+1].inWild
+	} else {
+		w.east = nil
+	}
+}